@@ -3,6 +3,7 @@ package muffet
 import (
 	"errors"
 	"fmt"
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
@@ -14,22 +15,77 @@ import (
 var usage = fmt.Sprintf(`Muffet, the web repairgirl
 
 Usage:
-	muffet [-c <concurrency>] [-e <pattern>...] [-f] [-j <header>...] [-l <times>] [-p] [-r] [-s] [-t <seconds>] [-v] [-x] <url>
+	muffet serve [--listen <address>]
+	muffet [-c <concurrency>] [-e <pattern>...] [-f] [-j <header>...] [-l <times>] [-p] [-r] [-s] [-t <seconds>] [-v] [-x]
+		[--by-target] [--check-mx] [--check-sitemap] [--checkpoint <file>] [--checkpoint-interval <seconds>] [--dial-mode <mode>] [--dns-cache-ttl <seconds>] [--dns-server <address>] [--format <format>] [--host-header <header>...] [--ignore-error <kind>...] [--max-body-size <bytes>] [--max-duration <seconds>] [--max-html-size <bytes>] [--profile <profile>] [--resolve <address>...] [--retries <times>] [--retry-error <kind>...] [--robots-txt-user-agent <agent>] [--scheme <scheme>...] [--sitemap <url>...] [--skip-disallowed-links] [--user-agent <agent>]
+		[--soft-404-body <pattern>...] [--soft-404-probe] [--soft-404-title <pattern>...]
+		[--metrics-file <file>] [--metrics-listen <address>] [--orphans-from-directory <dir>] [--orphans-from-sitemap] [--progress] [--resume <file>] [--warn-error <kind>...] [--warn-redirects] <url>
 
 Options:
 	-c, --concurrency <concurrency>   Roughly maximum number of concurrent HTTP connections. [default: %v]
+	--by-target                       List each broken link once with pages referring to it.
+	--check-mx                        Check MX records of domains in mailto links.
+	--check-sitemap                   Check entries in sitemap.xml and pages missing from it.
+	--checkpoint <file>               Save a state of a check into a file periodically.
+	--checkpoint-interval <seconds>   Set an interval of saving checkpoints in seconds. [default: %v]
+	--dial-mode <mode>                Dial hosts over "ipv4", "ipv6", "dual" stacks or "both" of them. [default: %v]
+	--dns-cache-ttl <seconds>         Cache addresses of hosts for given seconds or 0 not to cache them. [default: %v]
+	--dns-server <address>            Use a DNS server at an address.
 	-e, --exclude <pattern>...        Exclude URLs matched with given regular expressions.
 	-f, --ignore-fragments            Ignore URL fragments.
+	--format <format>                 Set an output format of "text", "json" or "html". [default: text]
 	-h, --help                        Show this help.
+	--host-header <header>...         Set custom headers for hosts in <host>=<name>: <value> format.
+	--ignore-error <kind>...          Ignore link errors of given kinds.
 	-j, --header <header>...          Set custom headers.
 	-l, --limit-redirections <times>  Limit a number of redirections. [default: %v]
+	--listen <address>                Listen on a given address in server mode. [default: %v]
+	--max-body-size <bytes>           Limit sizes of non-HTML responses read in bytes. [default: %v]
+	--max-duration <seconds>          Stop checking after given seconds and report incomplete results.
+	--max-html-size <bytes>           Report HTML pages larger than given bytes as errors. [default: %v]
+	--metrics-file <file>             Write metrics in the Prometheus text format into a file at the end.
+	--metrics-listen <address>        Serve metrics in the Prometheus text format at /metrics on an address.
+	--orphans-from-directory <dir>    Report files in a directory served at the URL but never linked.
+	--orphans-from-sitemap            Report pages in sitemap.xml never linked.
+	--progress                        Show progress on stderr.
+	--profile <profile>               Set a request profile of "default" or "browser". [default: %v]
 	-p, --one-page-only               Only check links found in the given URL, do not follow links.
 	-r, --follow-robots-txt           Follow robots.txt when scraping.
-	-s, --follow-sitemap-xml          Scrape only pages listed in sitemap.xml.
+	--resolve <address>...            Use addresses for hosts and ports in <host>:<port>:<address> format.
+	--resume <file>                   Resume a check saved in a checkpoint file.
+	--retries <times>                 Retry failed requests given times. [default: 0]
+	--retry-error <kind>...           Retry requests only on errors of given kinds. [default: %v]
+	--robots-txt-user-agent <agent>   Set a user agent matched in robots.txt. [default: %v]
+	--scheme <scheme>...              Check links of given schemes out of http, https, mailto, tel, ftp and file. [default: %v]
+	-s, --follow-sitemap-xml          Scrape only pages listed in sitemaps.
+	--sitemap <url>...                Use given sitemaps instead of ones in robots.txt or at /sitemap.xml.
+	--skip-disallowed-links           Skip checking links disallowed by robots.txt of their hosts.
+	--soft-404-body <pattern>...      Detect pages whose texts match <host>=<regexp> patterns as soft 404.
+	--soft-404-probe                  Detect pages similar to pages for nonexistent URLs as soft 404.
+	--soft-404-title <pattern>...     Detect pages whose titles match <host>=<regexp> patterns as soft 404.
 	-t, --timeout <seconds>           Set timeout for HTTP requests in seconds. [default: %v]
+	--user-agent <agent>              Set a User-Agent header.
 	-v, --verbose                     Show successful results too.
-	-x, --skip-tls-verification       Skip TLS certificates verification.`,
-	defaultConcurrency, defaultMaxRedirections, defaultTimeout.Seconds())
+	--warn-error <kind>...            Report link errors of given kinds without failing.
+	--warn-redirects                  Warn about permanent, insecure and cross-host redirects.
+	-x, --skip-tls-verification       Skip TLS certificates verification.
+
+Error kinds:
+	%v`,
+	defaultConcurrency,
+	defaultCheckpointInterval.Seconds(),
+	defaultDialMode,
+	defaultDNSCacheTTL.Seconds(),
+	defaultMaxRedirections,
+	defaultListenAddress,
+	defaultMaxBodySize,
+	defaultMaxHTMLSize,
+	defaultRequestProfile,
+	joinErrorKinds(defaultRetriedErrorKinds, " "),
+	defaultRobotsTxtUserAgent,
+	strings.Join(defaultSchemes, " "),
+	defaultTimeout.Seconds(),
+	joinErrorKinds(errorKinds, ", "))
 
 type arguments struct {
 	Concurrency      int
@@ -43,19 +99,96 @@ type arguments struct {
 	URL             string
 	Verbose,
 	SkipTLSVerification bool
-	OnePageOnly bool
+	OnePageOnly       bool
+	Format            string
+	IgnoredErrorKinds errorKindSet
+	Retries           int
+	RetriedErrorKinds,
+	WarnedErrorKinds errorKindSet
+	WarnRedirects bool
+	Soft404TitlePatterns,
+	Soft404BodyPatterns []soft404Pattern
+	Soft404Probe        bool
+	Serve               bool
+	ListenAddress       string
+	MaxDuration         time.Duration
+	CheckpointFile      string
+	CheckpointInterval  time.Duration
+	ResumedFile         string
+	Progress            bool
+	MetricsFile         string
+	MetricsAddress      string
+	ByTarget            bool
+	OrphanDirectory     string
+	OrphansFromSitemap  bool
+	CheckSitemap        bool
+	Sitemaps            []string
+	RobotsTxtUserAgent  string
+	SkipDisallowedLinks bool
+	Profile             string
+	UserAgent           string
+	HostHeaders         map[string]map[string]string
+	MaxHTMLSize         int
+	MaxBodySize         int
+	Schemes             []string
+	CheckMX             bool
+	DNSServer           string
+	DNSCacheTTL         time.Duration
+	DisableDNSCache     bool
+	Resolves            map[string]string
+	DialMode            string
 }
 
 func getArguments(ss []string) (arguments, error) {
-	args := parseArguments(usage, ss)
+	return convertArguments(parseArguments(usage, ss))
+}
+
+// getJobArguments parses arguments for jobs in server mode. Unlike
+// getArguments, it never exits a process on invalid arguments.
+func getJobArguments(ss []string) (arguments, error) {
+	p := docopt.Parser{HelpHandler: docopt.NoHelpHandler, SkipHelpFlags: true}
+	args, err := p.ParseArgs(usage, ss, "")
+
+	if err != nil {
+		return arguments{}, err
+	} else if args["serve"].(bool) {
+		return arguments{}, errors.New("server mode not allowed for jobs")
+	} else if args["--checkpoint"] != nil || args["--resume"] != nil ||
+		args["--metrics-file"] != nil || args["--orphans-from-directory"] != nil {
+		return arguments{}, errors.New("files not allowed for jobs")
+	} else if args["--metrics-listen"] != nil {
+		return arguments{}, errors.New("metrics listeners not allowed for jobs")
+	} else if args["--orphans-from-sitemap"].(bool) {
+		return arguments{}, errors.New("orphans not reported for jobs")
+	}
+
+	scs, _ := args["--scheme"].([]string)
+
+	for _, s := range scs {
+		if s == "file" {
+			return arguments{}, errors.New("file scheme not allowed for jobs")
+		}
+	}
+
+	return convertArguments(args)
+}
 
+func convertArguments(args map[string]interface{}) (arguments, error) {
 	c, err := parseInt(args["--concurrency"].(string))
 
 	if err != nil {
 		return arguments{}, err
 	}
 
-	ss, _ = args["--exclude"].([]string)
+	u, _ := args["<url>"].(string)
+	cf, _ := args["--checkpoint"].(string)
+	rf, _ := args["--resume"].(string)
+	mf, _ := args["--metrics-file"].(string)
+	od, _ := args["--orphans-from-directory"].(string)
+	sms, _ := args["--sitemap"].([]string)
+	ma, _ := args["--metrics-listen"].(string)
+
+	ss, _ := args["--exclude"].([]string)
 	rs, err := compileRegexps(ss)
 
 	if err != nil {
@@ -72,6 +205,64 @@ func getArguments(ss []string) (arguments, error) {
 		}
 	}
 
+	ss, _ = args["--host-header"].([]string)
+	hhs, err := parseHostHeaders(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	p := args["--profile"].(string)
+
+	if _, ok := requestProfiles[p]; !ok {
+		return arguments{}, fmt.Errorf("invalid request profile: %v", p)
+	}
+
+	ua, _ := args["--user-agent"].(string)
+
+	scs, _ := args["--scheme"].([]string)
+
+	for _, s := range scs {
+		if _, ok := schemes[s]; !ok {
+			return arguments{}, fmt.Errorf("unsupported scheme: %v", s)
+		}
+	}
+
+	ds, _ := args["--dns-server"].(string)
+
+	dt, err := parseInt(args["--dns-cache-ttl"].(string))
+
+	if err != nil {
+		return arguments{}, err
+	} else if dt < 0 {
+		return arguments{}, errors.New("DNS cache TTL must not be negative")
+	}
+
+	dm := args["--dial-mode"].(string)
+
+	if _, ok := dialModes[dm]; !ok {
+		return arguments{}, fmt.Errorf("invalid dial mode: %v", dm)
+	}
+
+	ss, _ = args["--resolve"].([]string)
+	rvs, err := parseResolves(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	hms, err := parseInt(args["--max-html-size"].(string))
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	bms, err := parseInt(args["--max-body-size"].(string))
+
+	if err != nil {
+		return arguments{}, err
+	}
+
 	r, err := parseInt(args["--limit-redirections"].(string))
 
 	if err != nil {
@@ -84,6 +275,71 @@ func getArguments(ss []string) (arguments, error) {
 		return arguments{}, err
 	}
 
+	f := args["--format"].(string)
+
+	if _, ok := outputFormats[f]; !ok {
+		return arguments{}, fmt.Errorf("invalid output format: %v", f)
+	}
+
+	ss, _ = args["--ignore-error"].([]string)
+	iks, err := parseErrorKinds(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	n, err := parseInt(args["--retries"].(string))
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	d := 0
+
+	if s, ok := args["--max-duration"].(string); ok {
+		d, err = parseInt(s)
+
+		if err != nil {
+			return arguments{}, err
+		}
+	}
+
+	ci, err := parseInt(args["--checkpoint-interval"].(string))
+
+	if err != nil {
+		return arguments{}, err
+	} else if ci <= 0 {
+		return arguments{}, errors.New("checkpoint interval must be positive")
+	}
+
+	ss, _ = args["--retry-error"].([]string)
+	rks, err := parseErrorKinds(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	ss, _ = args["--warn-error"].([]string)
+	wks, err := parseErrorKinds(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	ss, _ = args["--soft-404-title"].([]string)
+	tps, err := parseSoft404Patterns(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
+	ss, _ = args["--soft-404-body"].([]string)
+	bps, err := parseSoft404Patterns(ss)
+
+	if err != nil {
+		return arguments{}, err
+	}
+
 	return arguments{
 		c,
 		rs,
@@ -93,15 +349,52 @@ func getArguments(ss []string) (arguments, error) {
 		args["--ignore-fragments"].(bool),
 		r,
 		time.Duration(t) * time.Second,
-		args["<url>"].(string),
+		u,
 		args["--verbose"].(bool),
 		args["--skip-tls-verification"].(bool),
 		args["--one-page-only"].(bool),
+		f,
+		iks,
+		n,
+		rks,
+		wks,
+		args["--warn-redirects"].(bool),
+		tps,
+		bps,
+		args["--soft-404-probe"].(bool),
+		args["serve"].(bool),
+		args["--listen"].(string),
+		time.Duration(d) * time.Second,
+		cf,
+		time.Duration(ci) * time.Second,
+		rf,
+		args["--progress"].(bool),
+		mf,
+		ma,
+		args["--by-target"].(bool),
+		od,
+		args["--orphans-from-sitemap"].(bool),
+		args["--check-sitemap"].(bool),
+		sms,
+		args["--robots-txt-user-agent"].(string),
+		args["--skip-disallowed-links"].(bool),
+		p,
+		ua,
+		hhs,
+		hms,
+		bms,
+		scs,
+		args["--check-mx"].(bool),
+		ds,
+		time.Duration(dt) * time.Second,
+		dt == 0,
+		rvs,
+		dm,
 	}, nil
 }
 
 func parseArguments(u string, ss []string) map[string]interface{} {
-	args, err := docopt.ParseArgs(u, ss, "1.1.0")
+	args, err := docopt.ParseArgs(u, ss, version)
 
 	if err != nil {
 		panic(err)
@@ -146,3 +439,80 @@ func parseHeaders(ss []string) (map[string]string, error) {
 
 	return m, nil
 }
+
+// parseHostHeaders parses headers for hosts in <host>=<name>: <value> format.
+func parseHostHeaders(ss []string) (map[string]map[string]string, error) {
+	m := map[string]map[string]string{}
+
+	for _, s := range ss {
+		i := strings.IndexRune(s, '=')
+
+		if i <= 0 {
+			return nil, errors.New("invalid host header format")
+		}
+
+		hs, err := parseHeaders([]string{s[i+1:]})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := m[s[:i]]; !ok {
+			m[s[:i]] = map[string]string{}
+		}
+
+		for k, v := range hs {
+			m[s[:i]][k] = v
+		}
+	}
+
+	return m, nil
+}
+
+// parseResolves parses overrides of addresses of hosts in the format of
+// "<host>:<port>:<address>" into a map from pairs of hosts and ports.
+func parseResolves(ss []string) (map[string]string, error) {
+	m := map[string]string{}
+
+	for _, s := range ss {
+		ts := strings.SplitN(s, ":", 3)
+
+		if len(ts) != 3 || ts[0] == "" {
+			return nil, errors.New("invalid address override format")
+		} else if n, err := strconv.Atoi(ts[1]); err != nil || n <= 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid port in address override: %v", s)
+		}
+
+		a := strings.TrimSuffix(strings.TrimPrefix(ts[2], "["), "]")
+
+		if net.ParseIP(a) == nil {
+			return nil, fmt.Errorf("invalid IP address in address override: %v", s)
+		}
+
+		m[net.JoinHostPort(strings.ToLower(ts[0]), ts[1])] = a
+	}
+
+	return m, nil
+}
+
+func parseSoft404Patterns(ss []string) ([]soft404Pattern, error) {
+	ps := make([]soft404Pattern, 0, len(ss))
+
+	for _, s := range ss {
+		i := strings.IndexRune(s, '=')
+
+		if i <= 0 {
+			return nil, errors.New("invalid soft 404 pattern format")
+		}
+
+		r, err := regexp.Compile(s[i+1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		ps = append(ps, newSoft404Pattern(s[:i], r))
+	}
+
+	return ps, nil
+}
@@ -1,7 +1,9 @@
 package muffet
 
 import (
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,6 +31,37 @@ func TestGetArguments(t *testing.T) {
 		{"-v", "--ignore-fragments", "https://foo.com"},
 		{"-p", "https://foo.com"},
 		{"--one-page-only", "https://foo.com"},
+		{"--format", "json", "https://foo.com"},
+		{"--format", "html", "https://foo.com"},
+		{"--by-target", "https://foo.com"},
+		{"--check-sitemap", "https://foo.com"},
+		{"--sitemap", "https://foo.com/a.xml", "--sitemap", "https://foo.com/b.xml.gz", "https://foo.com"},
+		{"--orphans-from-sitemap", "https://foo.com"},
+		{"--robots-txt-user-agent", "googlebot", "--skip-disallowed-links", "https://foo.com"},
+		{"--profile", "browser", "--user-agent", "foo", "https://foo.com"},
+		{"--max-html-size", "1024", "--max-body-size", "2048", "https://foo.com"},
+		{"--host-header", "foo.com=MyHeader: foo", "https://foo.com"},
+		{"--scheme", "http", "--scheme", "ftp", "--scheme", "file", "https://foo.com"},
+		{"--check-mx", "--dns-server", "8.8.8.8", "https://foo.com"},
+		{"--dns-cache-ttl", "300", "--resolve", "foo.com:443:127.0.0.1", "https://foo.com"},
+		{"--dns-cache-ttl", "0", "https://foo.com"},
+		{"--dial-mode", "ipv6", "https://foo.com"},
+		{"--dial-mode", "both", "https://foo.com"},
+		{"--orphans-from-directory", "foo", "https://foo.com"},
+		{"--ignore-error", "dns", "--ignore-error", "tls", "https://foo.com"},
+		{"--retries", "3", "https://foo.com"},
+		{"--retries", "3", "--retry-error", "http-status", "https://foo.com"},
+		{"--warn-error", "http-status", "https://foo.com"},
+		{"--warn-redirects", "https://foo.com"},
+		{"--soft-404-title", "foo.com=Not Found", "--soft-404-body", "*=a=b", "https://foo.com"},
+		{"--soft-404-probe", "https://foo.com"},
+		{"--max-duration", "60", "https://foo.com"},
+		{"--checkpoint", "foo.json", "--checkpoint-interval", "10", "https://foo.com"},
+		{"--resume", "foo.json", "https://foo.com"},
+		{"--progress", "https://foo.com"},
+		{"--metrics-file", "foo.prom", "--metrics-listen", ":9090", "https://foo.com"},
+		{"serve"},
+		{"serve", "--listen", "127.0.0.1:3000"},
 	} {
 		_, err := getArguments(ss)
 		assert.Nil(t, err)
@@ -42,16 +75,100 @@ func TestGetArgumentsError(t *testing.T) {
 		{"-e", "(", "https://foo.com"},
 		{"-j", "MyHeader", "https://foo.com"},
 		{"--header", "MyHeader", "https://foo.com"},
+		{"--host-header", "MyHeader: foo", "https://foo.com"},
+		{"--host-header", "foo.com=MyHeader", "https://foo.com"},
+		{"--profile", "foo", "https://foo.com"},
+		{"--max-html-size", "foo", "https://foo.com"},
+		{"--max-body-size", "foo", "https://foo.com"},
+		{"--scheme", "gopher", "https://foo.com"},
+		{"--dns-cache-ttl", "foo", "https://foo.com"},
+		{"--dns-cache-ttl", "-1", "https://foo.com"},
+		{"--dial-mode", "ipv5", "https://foo.com"},
+		{"--resolve", "foo.com:127.0.0.1", "https://foo.com"},
 		{"-l", "foo", "https://foo.com"},
 		{"--limit-redirections", "foo", "https://foo.com"},
 		{"-t", "foo", "https://foo.com"},
 		{"--timeout", "foo", "https://foo.com"},
+		{"--format", "foo", "https://foo.com"},
+		{"--ignore-error", "foo", "https://foo.com"},
+		{"--retries", "foo", "https://foo.com"},
+		{"--retry-error", "foo", "https://foo.com"},
+		{"--warn-error", "foo", "https://foo.com"},
+		{"--soft-404-title", "foo.com", "https://foo.com"},
+		{"--soft-404-body", "foo.com=(", "https://foo.com"},
+		{"--max-duration", "foo", "https://foo.com"},
+		{"--checkpoint-interval", "foo", "https://foo.com"},
+		{"--checkpoint-interval", "0", "https://foo.com"},
 	} {
 		_, err := getArguments(ss)
 		assert.NotNil(t, err)
 	}
 }
 
+func TestGetArgumentsDefaultRetriedErrorKinds(t *testing.T) {
+	args, err := getArguments([]string{"https://foo.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, newErrorKindSet(defaultRetriedErrorKinds...), args.RetriedErrorKinds)
+}
+
+func TestGetArgumentsDefaultSchemes(t *testing.T) {
+	args, err := getArguments([]string{"https://foo.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, defaultSchemes, args.Schemes)
+}
+
+func TestGetArgumentsDNSCacheTTL(t *testing.T) {
+	args, err := getArguments([]string{"https://foo.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, defaultDNSCacheTTL, args.DNSCacheTTL)
+	assert.False(t, args.DisableDNSCache)
+
+	args, err = getArguments([]string{"--dns-cache-ttl", "0", "https://foo.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), args.DNSCacheTTL)
+	assert.True(t, args.DisableDNSCache)
+}
+
+func TestGetArgumentsWithServe(t *testing.T) {
+	args, err := getArguments([]string{"serve"})
+
+	assert.Nil(t, err)
+	assert.True(t, args.Serve)
+	assert.Equal(t, defaultListenAddress, args.ListenAddress)
+}
+
+func TestGetJobArguments(t *testing.T) {
+	args, err := getJobArguments([]string{"--concurrency=1", "https://foo.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, args.Concurrency)
+	assert.Equal(t, "https://foo.com", args.URL)
+}
+
+func TestGetJobArgumentsError(t *testing.T) {
+	for _, ss := range [][]string{
+		{},
+		{"--help"},
+		{"--foo", "https://foo.com"},
+		{"--concurrency=foo", "https://foo.com"},
+		{"serve"},
+		{"--checkpoint=foo.json", "https://foo.com"},
+		{"--resume=foo.json", "https://foo.com"},
+		{"--metrics-file=foo.prom", "https://foo.com"},
+		{"--orphans-from-directory=foo", "https://foo.com"},
+		{"--orphans-from-sitemap", "https://foo.com"},
+		{"--metrics-listen=:9090", "https://foo.com"},
+		{"--scheme=https", "--scheme=file", "https://foo.com"},
+	} {
+		_, err := getJobArguments(ss)
+		assert.NotNil(t, err)
+	}
+}
+
 func TestParseArguments(t *testing.T) {
 	assert.Panics(t, func() {
 		parseArguments("", nil)
@@ -87,3 +204,51 @@ func TestParseHeadersError(t *testing.T) {
 	_, err := parseHeaders([]string{"MyHeader"})
 	assert.NotNil(t, err)
 }
+
+func TestParseHostHeaders(t *testing.T) {
+	hs, err := parseHostHeaders([]string{"foo.com=MyHeader: foo", "foo.com=YourHeader: a=b", "bar.com=MyHeader: bar"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"foo.com": {"MyHeader": "foo", "YourHeader": "a=b"},
+		"bar.com": {"MyHeader": "bar"},
+	}, hs)
+}
+
+func TestParseResolves(t *testing.T) {
+	m, err := parseResolves([]string{"foo.com:443:127.0.0.1", "Bar.com:80:[::1]"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"foo.com:443": "127.0.0.1", "bar.com:80": "::1"}, m)
+}
+
+func TestParseResolvesError(t *testing.T) {
+	for _, s := range []string{
+		"foo.com",
+		"foo.com:443",
+		":443:127.0.0.1",
+		"foo.com:foo:127.0.0.1",
+		"foo.com:0:127.0.0.1",
+		"foo.com:443:bar.com",
+	} {
+		_, err := parseResolves([]string{s})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestParseSoft404Patterns(t *testing.T) {
+	ps, err := parseSoft404Patterns([]string{"foo.com=Not Found", "*=a=b"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []soft404Pattern{
+		newSoft404Pattern("foo.com", regexp.MustCompile("Not Found")),
+		newSoft404Pattern("*", regexp.MustCompile("a=b")),
+	}, ps)
+}
+
+func TestParseSoft404PatternsError(t *testing.T) {
+	for _, s := range []string{"foo", "=foo", "foo.com=("} {
+		_, err := parseSoft404Patterns([]string{s})
+		assert.NotNil(t, err)
+	}
+}
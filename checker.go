@@ -1,133 +1,500 @@
 package muffet
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"net/url"
 	"sync"
+	"sync/atomic"
 
-	"github.com/fatih/color"
 	"github.com/valyala/fasthttp"
 )
 
 type checker struct {
 	fetcher
-	daemons      daemons
-	urlInspector urlInspector
-	results      chan pageResult
-	donePages    concurrentStringSet
+	workers           workerPool
+	urlInspector      urlInspector
+	robotsTxt         *robotsTxtCache
+	results           chan pageResult
+	donePages         concurrentStringSet
+	ignoredErrorKinds errorKindSet
+	warnRedirects     bool
+	sitemapClient     sitemapClient
+	sitemaps          []string
 }
 
 func newChecker(s string, o checkerOptions) (checker, error) {
-	o.Initialize()
+	return newCheckerWithCache(s, o, newCache())
+}
+
+// newCheckerWithCache creates a checker with a cache shared with others.
+func newCheckerWithCache(s string, o checkerOptions, ca cache) (checker, error) {
+	c, p, err := newUnstartedChecker(s, o, ca)
+
+	if err != nil {
+		return checker{}, err
+	}
+
+	c.addPage(p, 0)
+
+	return c, nil
+}
+
+// resumeChecker creates a checker which continues a check saved in a
+// checkpoint. Results in the checkpoint are sent again before new ones.
+func resumeChecker(s string, o checkerOptions, cp checkpoint) (checker, error) {
+	c, p, err := newUnstartedChecker(s, o, newCache())
+
+	if err != nil {
+		return checker{}, err
+	}
+
+	for _, u := range cp.Pages() {
+		c.donePages.Add(u)
+	}
+
+	c.statistics.AddPages(len(cp.Pages()))
+
+	// Results are sent in a task as the result channel is bounded and not
+	// consumed yet.
+	c.workers.Add(linkTaskPriority, func(context.Context) {
+		for _, r := range cp.Results() {
+			c.sendResult(r)
+		}
+	})
 
-	c := &fasthttp.Client{
-		MaxConnsPerHost: o.Concurrency,
+	c.addPage(p, 0)
+
+	// Depths of pending pages other than a root page are unknown.
+	for _, u := range cp.PendingPages() {
+		if u == p.URL().String() {
+			c.workers.AddPage(p, 0)
+			continue
+		}
+
+		u := u
+
+		c.workers.Add(linkTaskPriority, func(ctx context.Context) {
+			c.loadPage(ctx, u, 1)
+		})
+	}
+
+	return c, nil
+}
+
+// newHTTPClient creates an HTTP client reading responses up to a size.
+func newHTTPClient(o checkerOptions, d fasthttp.DialFunc, n int) *fasthttp.Client {
+	return &fasthttp.Client{
+		Dial:                d,
+		MaxConnsPerHost:     o.Concurrency,
+		MaxResponseBodySize: n,
 		TLSConfig: &tls.Config{
 			InsecureSkipVerify: o.SkipTLSVerification,
 		},
 	}
+}
+
+func newUnstartedChecker(s string, o checkerOptions, ca cache) (checker, *page, error) {
+	o.Initialize()
+
+	d := newDialFunc(o.fetcherOptions)
+	c := newHTTPClient(o, d, o.MaxBodySize)
+	// Bodies larger than the size are streamed so that HTML pages are read up
+	// to their own limit without being requested again.
+	c.StreamResponseBody = true
 	f := newFetcher(c, o.fetcherOptions)
+	f.htmlClient = newHTTPClient(o, d, o.MaxHTMLSize)
+	f.cache = ca
 	r, err := f.Fetch(s)
 
 	if err != nil {
-		return checker{}, err
+		return checker{}, nil, err
 	}
 
 	p, ok := r.Page()
 
 	if !ok {
-		return checker{}, errors.New("non-HTML page")
+		return checker{}, nil, errors.New("non-HTML page")
+	}
+
+	sc := newSitemapClient(newHTTPClient(o, d, maxSitemapSize), o.fetcherOptions)
+	rc := newRobotsTxtCache(sc, o.RobotsTxtUserAgent)
+	ur, lr := (*robotsTxtCache)(nil), (*robotsTxtCache)(nil)
+
+	if o.FollowRobotsTxt {
+		ur = &rc
+	}
+
+	if o.SkipDisallowedLinks {
+		lr = &rc
 	}
 
-	ui, err := newURLInspector(c, p.URL().String(), o.FollowRobotsTxt, o.FollowSitemapXML)
+	ui, err := newURLInspector(sc, p.URL().String(), ur, o.FollowSitemapXML, o.Sitemaps)
 
 	if err != nil {
-		return checker{}, err
+		return checker{}, nil, err
 	}
 
 	ch := checker{
 		f,
-		newDaemons(o.Concurrency),
+		newWorkerPool(o.Concurrency, maxPagesInMemory),
 		ui,
+		lr,
 		make(chan pageResult, o.Concurrency),
 		newConcurrentStringSet(),
+		o.IgnoredErrorKinds,
+		o.WarnRedirects,
+		sc,
+		o.Sitemaps,
 	}
 
-	ch.addPage(p)
-
-	return ch, nil
+	return ch, p, nil
 }
 
 func (c checker) Results() <-chan pageResult {
 	return c.results
 }
 
-func (c checker) Check() {
-	c.daemons.Run()
+// Pages returns URLs of pages found so far including ones not checked yet.
+func (c checker) Pages() []string {
+	return c.donePages.Values()
+}
+
+// Warnings returns warnings on creating a checker.
+func (c checker) Warnings() []string {
+	return c.urlInspector.Warnings()
+}
+
+// SitemapURLs returns URLs listed in sitemaps of a website and warnings on
+// sitemaps not found.
+func (c checker) SitemapURLs(s string) ([]string, []string, error) {
+	_, es, ms, err := c.loadSitemaps(s)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	us := make([]string, 0, len(es))
+
+	for _, e := range es {
+		us = append(us, e.URL())
+	}
+
+	return us, sitemapWarnings(ms), nil
+}
+
+// loadSitemaps loads entries in sitemaps of a website. It also returns URLs
+// of the sitemaps and ones not found. The URLs of the sitemaps are returned
+// even if they fail to be loaded.
+func (c checker) loadSitemaps(s string) ([]string, []sitemapEntry, []string, error) {
+	ss, err := discoverSitemaps(c.sitemapClient, s, c.sitemaps)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	es, ms, err := loadSitemaps(c.sitemapClient, ss)
+
+	if err != nil {
+		return ss, nil, nil, err
+	}
+
+	return ss, es, ms, nil
+}
+
+// CheckSitemap checks entries in sitemaps of a website and pages found so
+// far which are missing from them. A result has a URL of the first sitemap.
+// Sitemaps failing to be loaded are reported as errors in the result. Entries
+// not checked before a context is canceled are left out of it.
+func (c checker) CheckSitemap(ctx context.Context, s string) (pageResult, error) {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return pageResult{}, err
+	}
+
+	ss, es, ms, err := c.loadSitemaps(s)
+
+	if err != nil && len(ss) != 0 {
+		l := c.newLinkResult(ss[0], fetchResult{}, newFetchError(ErrorKindSitemap, err))
+		return newPageResult(ss[0], []linkResult{l}), nil
+	} else if err != nil {
+		return pageResult{}, err
+	}
+
+	us, ns := []string(nil), map[string]int{}
+
+	for _, e := range es {
+		if ns[e.URL()] == 0 {
+			us = append(us, e.URL())
+		}
+
+		ns[e.URL()]++
+	}
+
+	ls, oks := make([]linkResult, len(us)), make([]bool, len(us))
+	g, sem := &sync.WaitGroup{}, newSemaphore(c.fetcher.options.Concurrency)
+
+	for i, s := range us {
+		g.Add(1)
+		sem.Request()
+
+		go func(i int, s string) {
+			defer g.Done()
+			defer sem.Release()
+
+			ls[i], oks[i] = c.checkSitemapEntry(ctx, u.Host, s)
+
+			if n := ns[s]; n > 1 {
+				ls[i] = ls[i].Warn(fmt.Sprintf("listed %v times in sitemap", n))
+			}
+		}(i, s)
+	}
+
+	g.Wait()
+
+	ls = filterLinkResults(ls, oks)
+
+	for _, m := range ms {
+		ls = append(ls, c.newLinkResult(m, fetchResult{}, nil).Warn("sitemap not found"))
+	}
+
+	// Pages are not compared with nonexistent sitemaps.
+	if len(es) == 0 {
+		return newPageResult(ss[0], ls), nil
+	}
+
+	ps := map[string]struct{}{}
+
+	for _, s := range us {
+		ps[normalizeResourceURL(s)] = struct{}{}
+	}
+
+	for _, p := range c.Pages() {
+		if _, ok := ps[normalizeResourceURL(p)]; !ok {
+			r, err := c.fetcher.Fetch(p)
+			ls = append(ls, c.newLinkResult(p, r, err).Warn("missing from sitemap"))
+		}
+	}
+
+	return newPageResult(ss[0], ls), nil
+}
+
+// checkSitemapEntry checks an entry in a sitemap of a website at a host. It
+// returns false if the entry is not checked as a context is canceled.
+func (c checker) checkSitemapEntry(ctx context.Context, h, s string) (linkResult, bool) {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return c.newLinkResult(s, fetchResult{}, newFetchError(ErrorKindParse, err)), true
+	} else if u.Host != h {
+		return c.newLinkResult(s, fetchResult{}, newFetchError(ErrorKindSitemap, errors.New("outside of site"))), true
+	} else if ctx.Err() != nil {
+		return linkResult{}, false
+	}
+
+	r, err := c.fetcher.Fetch(s)
+	l := c.newLinkResult(s, r, err)
+
+	if err != nil {
+		return l, true
+	} else if _, ok := r.Page(); !ok {
+		return c.newLinkResult(s, r, newFetchError(ErrorKindSitemap, errors.New("non-HTML page"))), true
+	} else if r.StatusCode() != 200 {
+		return c.newLinkResult(s, r, newFetchError(ErrorKindSitemap, fmt.Errorf("status %v", r.StatusCode()))), true
+	} else if rs := r.Redirects(); len(rs) != 0 {
+		l = l.Warn(fmt.Sprintf("redirect in sitemap to %v", rs[len(rs)-1].Location()))
+	}
+
+	return l, true
+}
+
+// filterLinkResults returns link results flagged as true.
+func filterLinkResults(ls []linkResult, oks []bool) []linkResult {
+	rs := make([]linkResult, 0, len(ls))
+
+	for i, l := range ls {
+		if oks[i] {
+			rs = append(rs, l)
+		}
+	}
+
+	return rs
+}
+
+// Check checks pages until all of them are checked or a context is canceled.
+// When the context is canceled, links not fetched yet are not checked.
+func (c checker) Check(ctx context.Context) {
+	c.workers.Run(ctx, func(ctx context.Context, p *page, d int) {
+		if ctx.Err() == nil {
+			c.checkPage(ctx, p, d)
+		}
+	})
 
 	close(c.results)
 }
 
-func (c checker) checkPage(p *page) {
-	us := p.Links()
+// Err returns an error which makes a check incomplete other than cancellation
+// of its context.
+func (c checker) Err() error {
+	return c.workers.Err()
+}
+
+// checkPage queues tasks of fetching links in a page at a given depth. A
+// result of the page is sent when all of them finish.
+// Tasks do not refer to the page so that its links are released early.
+func (c checker) checkPage(ctx context.Context, p *page, d int) {
+	s, us, ls := p.URL().String(), p.Links(), p.Sources()
+
+	if len(us) == 0 {
+		c.sendResult(newPageResult(s, []linkResult{}))
+		return
+	}
 
-	sc := make(chan string, len(us))
-	ec := make(chan string, len(us))
-	w := sync.WaitGroup{}
+	lc := make(chan linkResult, len(us))
+	n := int32(len(us))
+	done := func() {
+		if atomic.AddInt32(&n, -1) == 0 {
+			c.sendResult(newPageResult(s, linkResultChannelToSlice(lc)))
+		}
+	}
 
 	for u, err := range us {
 		if err != nil {
-			ec <- formatLinkError(u, err)
+			lc <- c.newLinkResult(u, fetchResult{}, err).WithSources(ls[u])
+			done()
 			continue
 		}
 
-		w.Add(1)
+		u, ss := u, ls[u]
 
-		go func(u string) {
-			defer w.Done()
+		c.workers.Add(linkTaskPriority, func(ctx context.Context) {
+			defer done()
 
-			r, err := c.fetcher.Fetch(u)
-
-			if err == nil {
-				sc <- formatLinkSuccess(u, r.StatusCode())
-			} else {
-				ec <- formatLinkError(u, err)
+			if ctx.Err() != nil {
+				return
+			} else if s := urlScheme(u); !isKnownScheme(s) {
+				lc <- c.newLinkResult(u, fetchResult{}, nil).Warn(fmt.Sprintf("unknown scheme %v", s)).WithSources(ss)
+				return
+			} else if !c.allowedByRobotsTxt(u) {
+				err := newFetchError(ErrorKindExcluded, errors.New("disallowed by robots.txt"))
+				lc <- c.newLinkResult(u, fetchResult{}, err).WithSources(ss)
+				return
 			}
 
+			r, err := c.fetcher.Fetch(u)
+			lc <- c.newLinkResult(u, r, err).WithSources(ss)
+
 			// only consider adding the page to the list if we're recursing
 			if !c.fetcher.options.OnePageOnly {
 				if p, ok := r.Page(); ok && c.urlInspector.Inspect(p.URL()) {
-					c.addPage(p)
+					c.addPage(p, d+1)
 				}
 			}
-		}(u)
+		})
 	}
+}
 
-	w.Wait()
+// allowedByRobotsTxt returns true if a link is allowed by robots.txt of its
+// host or robots.txt is not followed for links.
+func (c checker) allowedByRobotsTxt(s string) bool {
+	if c.robotsTxt == nil {
+		return true
+	}
+
+	u, err := url.Parse(s)
 
-	c.results <- newPageResult(p.URL().String(), stringChannelToSlice(sc), stringChannelToSlice(ec))
+	return err != nil || c.robotsTxt.Allowed(u)
 }
 
-func (c checker) addPage(p *page) {
-	if !c.donePages.Add(p.URL().String()) {
-		c.daemons.Add(func() { c.checkPage(p) })
+func (c checker) newLinkResult(u string, fr fetchResult, err error) linkResult {
+	r := newLinkResult(u, fr.StatusCode(), err).WithRedirects(fr.Redirects())
+
+	if c.warnRedirects {
+		r = r.Warn(redirectWarnings(fr.Redirects())...)
+	}
+
+	if k := r.ErrorKind(); k == ErrorKindExcluded || c.ignoredErrorKinds.Contains(k) {
+		return r.Ignore()
 	}
+
+	return r
+}
+
+func (c checker) sendResult(r pageResult) {
+	c.statistics.AddResult(r)
+	c.metrics.AddResult(r)
+	c.results <- r
+}
+
+func (c checker) Metrics() *metrics {
+	return c.metrics
+}
+
+// Statistics returns a snapshot of live counters of a check.
+func (c checker) Statistics() statisticsSnapshot {
+	return c.statistics.Snapshot()
 }
 
-func stringChannelToSlice(sc <-chan string) []string {
-	ss := make([]string, 0, len(sc))
+// addPage queues a page at a depth if it is not checked yet. Compacted pages
+// from a cache are skipped if this checker has got their links already.
+// Otherwise, they are fetched again as others sharing the cache have got them.
+func (c checker) addPage(p *page, d int) {
+	u := p.URL().String()
+
+	if p.Compacted() && c.fetchedPages.Contains(u) || c.donePages.Add(u) {
+		return
+	}
+
+	c.statistics.AddPages(1)
 
-	for i := 0; i < cap(ss); i++ {
-		ss = append(ss, <-sc)
+	if !p.Compacted() {
+		c.workers.AddPage(p, d)
+		return
 	}
 
-	return ss
+	c.workers.Add(linkTaskPriority, func(ctx context.Context) {
+		c.loadPage(ctx, u, d)
+	})
 }
 
-func formatLinkSuccess(u string, s int) string {
-	return color.GreenString("%v", s) + "\t" + u
+// loadPage fetches a page not loaded by this checker yet and queues it. If it
+// fails, a result of the page with an error is sent so that it is never
+// pending.
+func (c checker) loadPage(ctx context.Context, u string, d int) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	r, err := c.fetcher.Fetch(u)
+
+	// Links of a page are dropped if others have fetched it first.
+	if p, ok := r.Page(); err == nil && ok && p.Compacted() {
+		r, err = c.fetcher.sendRequestWithRetries(u)
+	}
+
+	if err == nil {
+		p, ok := r.Page()
+
+		if ok {
+			c.workers.AddPage(p, d)
+			return
+		}
+
+		err = newFetchError(ErrorKindOther, errors.New("non-HTML page"))
+	}
+
+	c.sendResult(newPageResult(u, []linkResult{c.newLinkResult(u, r, err)}))
 }
 
-func formatLinkError(u string, err error) string {
-	return color.RedString(err.Error()) + "\t" + u
+func linkResultChannelToSlice(lc <-chan linkResult) []linkResult {
+	ls := make([]linkResult, 0, len(lc))
+
+	for i := 0; i < cap(ls); i++ {
+		ls = append(ls, <-lc)
+	}
+
+	return ls
 }
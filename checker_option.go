@@ -5,4 +5,21 @@ type checkerOptions struct {
 	FollowRobotsTxt,
 	FollowSitemapXML,
 	SkipTLSVerification bool
+	IgnoredErrorKinds errorKindSet
+	WarnRedirects     bool
+	// Sitemaps are URLs of sitemaps used instead of discovered ones.
+	Sitemaps []string
+	// RobotsTxtUserAgent is a user agent matched with groups in robots.txt.
+	RobotsTxtUserAgent string
+	// SkipDisallowedLinks makes a checker skip links disallowed by
+	// robots.txt of their hosts.
+	SkipDisallowedLinks bool
+}
+
+func (o *checkerOptions) Initialize() {
+	o.fetcherOptions.Initialize()
+
+	if o.RobotsTxtUserAgent == "" {
+		o.RobotsTxtUserAgent = defaultRobotsTxtUserAgent
+	}
 }
@@ -11,4 +11,5 @@ func TestCheckerOptionsInitialize(t *testing.T) {
 	o.Initialize()
 
 	assert.Equal(t, defaultConcurrency, o.Concurrency)
+	assert.Equal(t, defaultRobotsTxtUserAgent, o.RobotsTxtUserAgent)
 }
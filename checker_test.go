@@ -1,8 +1,12 @@
 package muffet
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,8 +30,8 @@ func TestNewCheckerWithNonHTMLPage(t *testing.T) {
 }
 
 func TestNewCheckerWithMissingSitemapXML(t *testing.T) {
-	_, err := newChecker(missingMetadataURL, checkerOptions{FollowSitemapXML: true})
-	assert.Equal(t, "sitemap not found", err.Error())
+	_, err := newChecker(noResponseURL, checkerOptions{FollowSitemapXML: true})
+	assert.NotNil(t, err)
 }
 
 func TestCheckerCheck(t *testing.T) {
@@ -35,7 +39,7 @@ func TestCheckerCheck(t *testing.T) {
 		c, err := newChecker(s, checkerOptions{})
 		assert.Nil(t, err)
 
-		go c.Check()
+		go c.Check(context.Background())
 
 		for r := range c.Results() {
 			assert.True(t, r.OK())
@@ -43,10 +47,27 @@ func TestCheckerCheck(t *testing.T) {
 	}
 }
 
+func TestCheckerCheckWithResolves(t *testing.T) {
+	_, err := newChecker("http://foo.invalid:8080", checkerOptions{})
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+
+	c, err := newChecker(
+		"http://foo.invalid:8080",
+		checkerOptions{fetcherOptions: fetcherOptions{Resolves: map[string]string{"foo.invalid:8080": "127.0.0.1"}}},
+	)
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for r := range c.Results() {
+		assert.True(t, r.OK())
+	}
+}
+
 func TestCheckerCheckMultiplePages(t *testing.T) {
 	c, _ := newChecker(rootURL, checkerOptions{})
 
-	go c.Check()
+	go c.Check(context.Background())
 
 	i := 0
 
@@ -58,7 +79,7 @@ func TestCheckerCheckMultiplePages(t *testing.T) {
 }
 
 func TestCheckerCheckPage(t *testing.T) {
-	c, _ := newChecker(rootURL, checkerOptions{})
+	c, _, _ := newUnstartedChecker(rootURL, checkerOptions{}, newCache())
 
 	r, err := c.fetcher.Fetch(existentURL)
 	assert.Nil(t, err)
@@ -66,7 +87,8 @@ func TestCheckerCheckPage(t *testing.T) {
 	p, ok := r.Page()
 	assert.True(t, ok)
 
-	go c.checkPage(p)
+	c.checkPage(context.Background(), p, 0)
+	go c.Check(context.Background())
 
 	assert.True(t, (<-c.Results()).OK())
 }
@@ -79,14 +101,36 @@ func TestCheckerCheckWithExcludedURLs(t *testing.T) {
 		fetcherOptions: fetcherOptions{ExcludedPatterns: []*regexp.Regexp{r}},
 	})
 
-	go c.Check()
+	go c.Check(context.Background())
 
-	assert.Equal(t, 2, strings.Count((<-c.Results()).String(true), "\n"))
+	assert.Equal(t, 3, strings.Count((<-c.Results()).String(true), "\n"))
+}
+
+func TestCheckerCheckWithDisallowedLinks(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{RobotsTxtUserAgent: "strict", SkipDisallowedLinks: true})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	i := 0
+
+	for r := range c.Results() {
+		assert.True(t, r.OK())
+
+		for _, l := range r.Links() {
+			assert.True(t, l.Ignored())
+			assert.Equal(t, "disallowed by robots.txt", l.Error().Error())
+		}
+
+		i++
+	}
+
+	assert.Equal(t, 1, i)
 }
 
 func TestCheckerCheckPageError(t *testing.T) {
 	for _, s := range []string{erroneousURL} {
-		c, _ := newChecker(rootURL, checkerOptions{})
+		c, _, _ := newUnstartedChecker(rootURL, checkerOptions{}, newCache())
 
 		r, err := c.fetcher.Fetch(s)
 		assert.Nil(t, err)
@@ -94,49 +138,413 @@ func TestCheckerCheckPageError(t *testing.T) {
 		p, ok := r.Page()
 		assert.True(t, ok)
 
-		go c.checkPage(p)
+		c.checkPage(context.Background(), p, 0)
+		go c.Check(context.Background())
 
 		assert.False(t, (<-c.Results()).OK())
 	}
 }
 
-func TestStringChannelToSlice(t *testing.T) {
+func TestCheckerCheckWithIgnoredErrorKinds(t *testing.T) {
+	c, _ := newChecker(erroneousURL, checkerOptions{
+		IgnoredErrorKinds: newErrorKindSet(ErrorKindHTTPStatus, ErrorKindMissingFragment, ErrorKindParse),
+	})
+
+	go c.Check(context.Background())
+
+	r := <-c.Results()
+
+	assert.True(t, r.OK())
+	assert.Equal(t, 1, strings.Count(r.String(true), "\n"))
+}
+
+func TestCheckerCheckPageWithRedirectWarnings(t *testing.T) {
+	for _, c := range []struct {
+		url     string
+		warning string
+	}{
+		{permanentRedirectURL, "permanent redirect"},
+		{crossHostRedirectURL, "cross-host redirect"},
+		{insecureRedirectURL, "insecure redirect"},
+	} {
+		for _, b := range []bool{false, true} {
+			ch, _, err := newUnstartedChecker(rootURL, checkerOptions{SkipTLSVerification: true, WarnRedirects: b}, newCache())
+			assert.Nil(t, err)
+
+			p, err := newPage(rootURL, nil, newScraper(nil, defaultSchemes))
+			assert.Nil(t, err)
+
+			p.links = map[string]error{c.url: nil}
+
+			ch.checkPage(context.Background(), p, 0)
+			go ch.Check(context.Background())
+
+			r := <-ch.Results()
+
+			assert.True(t, r.OK())
+			assert.Equal(t, b, r.HasWarnings())
+			assert.Equal(t, b, strings.Contains(r.String(false), c.warning))
+		}
+	}
+}
+
+func TestCheckerCheckPageWithUnknownSchemes(t *testing.T) {
+	c, _, err := newUnstartedChecker(rootURL, checkerOptions{}, newCache())
+	assert.Nil(t, err)
+
+	p, err := newPage(rootURL, []byte(`<a href="gopher://localhost/foo" />`), c.fetcher.scraper)
+	assert.Nil(t, err)
+
+	c.checkPage(context.Background(), p, 0)
+	go c.Check(context.Background())
+
+	r := <-c.Results()
+
+	assert.True(t, r.OK())
+	assert.True(t, r.HasWarnings())
+	assert.Equal(t, []string{"unknown scheme gopher"}, r.Links()[0].Warnings())
+}
+
+func TestCheckerCheckWithLinkSources(t *testing.T) {
+	c, err := newChecker(erroneousURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for _, l := range (<-c.Results()).Links() {
+		if l.URL() != nonExistentURL {
+			continue
+		}
+
+		assert.Equal(t, 1, len(l.Sources()))
+		assert.Equal(t, "/bar", l.Sources()[0].Value())
+		assert.Equal(t, 4, l.Sources()[0].Line())
+	}
+}
+
+func TestCheckerStatistics(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for range c.Results() {
+	}
+
+	s := c.Statistics()
+
+	assert.Equal(t, 2, s.PagesFound)
+	assert.Equal(t, 2, s.PagesChecked)
+	assert.Equal(t, 2, s.LinksChecked)
+	assert.Zero(t, s.Errors)
+	assert.NotZero(t, s.Requests)
+	assert.Equal(t, map[string]int{}, s.InFlightRequests)
+}
+
+func TestCheckerCheckWithSharedCache(t *testing.T) {
+	ca := newCache()
+
+	for i := 0; i < 2; i++ {
+		c, err := newCheckerWithCache(rootURL, checkerOptions{}, ca)
+		assert.Nil(t, err)
+
+		go c.Check(context.Background())
+
+		n := 0
+
+		for r := range c.Results() {
+			n += len(r.Links())
+		}
+
+		assert.Equal(t, 2, n)
+	}
+}
+
+func TestCheckerLoadPage(t *testing.T) {
+	c, _, err := newUnstartedChecker(rootURL, checkerOptions{}, newCache())
+	assert.Nil(t, err)
+
+	r, err := c.fetcher.sendRequestWithRetries(fragmentURL)
+	assert.Nil(t, err)
+
+	p, ok := r.Page()
+	assert.True(t, ok)
+
+	c.loadPage(context.Background(), fragmentURL, 1)
+	go c.Check(context.Background())
+
+	x := <-c.Results()
+
+	assert.Equal(t, fragmentURL, x.URL())
+	assert.Equal(t, len(p.Links()), len(x.Links()))
+}
+
+func TestCheckerLoadPageError(t *testing.T) {
+	for _, s := range []string{nonExistentURL, robotsTxtURL} {
+		c, _, err := newUnstartedChecker(rootURL, checkerOptions{}, newCache())
+		assert.Nil(t, err)
+
+		c.statistics.AddPages(1)
+		c.loadPage(context.Background(), s, 1)
+		go c.Check(context.Background())
+
+		r := <-c.Results()
+
+		assert.Equal(t, s, r.URL())
+		assert.False(t, r.OK())
+		assert.Equal(t, 1, len(r.Links()))
+		assert.Equal(t, 0, c.Statistics().PagesQueued())
+	}
+}
+
+func TestResumeChecker(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	rs := []pageResult{}
+
+	for r := range c.Results() {
+		rs = append(rs, r)
+	}
+
+	assert.True(t, len(rs) > 1)
+
+	for _, cp := range []checkpoint{
+		newCheckpoint(rootURL, nil, nil),
+		newCheckpoint(rootURL, c.Pages(), nil),
+		newCheckpoint(rootURL, c.Pages(), rs[:1]),
+		newCheckpoint(rootURL, c.Pages(), rs),
+	} {
+		c, err := resumeChecker(rootURL, checkerOptions{}, cp)
+		assert.Nil(t, err)
+
+		go c.Check(context.Background())
+
+		us := map[string]int{}
+
+		for r := range c.Results() {
+			assert.True(t, r.OK())
+			us[r.URL()]++
+		}
+
+		assert.Equal(t, len(rs), len(us))
+
+		for _, r := range rs {
+			assert.Equal(t, 1, us[r.URL()])
+		}
+	}
+}
+
+func TestResumeCheckerError(t *testing.T) {
+	_, err := resumeChecker(":", checkerOptions{}, newCheckpoint(":", nil, nil))
+	assert.NotNil(t, err)
+}
+
+func TestCheckerSitemapURLs(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	us, ws, err := c.SitemapURLs(existentURL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{rootURL, existentURL}, us)
+	assert.Empty(t, ws)
+
+	us, ws, err = c.SitemapURLs(missingMetadataURL)
+	assert.Nil(t, err)
+	assert.Empty(t, us)
+	assert.Equal(t, 1, len(ws))
+
+	_, _, err = c.SitemapURLs(noResponseURL)
+	assert.NotNil(t, err)
+
+	_, _, err = c.SitemapURLs(":")
+	assert.NotNil(t, err)
+}
+
+func TestCheckerSitemapURLsWithSitemaps(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{Sitemaps: []string{sitemapCheckURL + "/sitemap-gzipped.xml.gz"}})
+	assert.Nil(t, err)
+
+	us, _, err := c.SitemapURLs(rootURL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{sitemapCheckURL + "/baz"}, us)
+}
+
+func TestCheckerWarnings(t *testing.T) {
+	c, err := newChecker(missingMetadataURL, checkerOptions{FollowSitemapXML: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(c.Warnings()))
+}
+
+func TestCheckerCheckSitemap(t *testing.T) {
+	c, err := newChecker(sitemapCheckURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for range c.Results() {
+	}
+
+	r, err := c.CheckSitemap(context.Background(), sitemapCheckURL)
+	assert.Nil(t, err)
+	assert.Equal(t, sitemapCheckURL+"/sitemap.xml", r.URL())
+	assert.False(t, r.OK())
+
+	ls := map[string]linkResult{}
+
+	for _, l := range r.Links() {
+		ls[l.URL()] = l
+	}
+
+	assert.Equal(t, 9, len(ls))
+	assert.True(t, ls[sitemapCheckURL+"/"].OK())
+	assert.True(t, ls[sitemapCheckURL+"/foo"].OK())
+	assert.Equal(t, []string{"listed 2 times in sitemap"}, ls[sitemapCheckURL+"/foo"].Warnings())
+	assert.True(t, ls[sitemapCheckURL+"/redirect"].OK())
+	assert.Equal(t, []string{"redirect in sitemap to " + sitemapCheckURL + "/foo"}, ls[sitemapCheckURL+"/redirect"].Warnings())
+	assert.Equal(t, ErrorKindSitemap, ls[sitemapCheckURL+"/image.png"].ErrorKind())
+	assert.Equal(t, ErrorKindHTTPStatus, ls[sitemapCheckURL+"/missing"].ErrorKind())
+	assert.Equal(t, ErrorKindSitemap, ls[rootURL].ErrorKind())
+	assert.True(t, ls[sitemapCheckURL+"/bar"].OK())
+	assert.Equal(t, []string{"missing from sitemap"}, ls[sitemapCheckURL+"/bar"].Warnings())
+	assert.True(t, ls[sitemapCheckURL+"/baz"].OK())
+	assert.Equal(t, []string{"sitemap not found"}, ls[sitemapCheckURL+"/sitemap-missing.xml"].Warnings())
+}
+
+func TestCheckerCheckSitemapWithConcurrency(t *testing.T) {
+	c, err := newChecker(sitemapCheckURL, checkerOptions{fetcherOptions: fetcherOptions{Concurrency: 1}})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for range c.Results() {
+	}
+
+	r, err := c.CheckSitemap(context.Background(), sitemapCheckURL)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, len(r.Links()))
+}
+
+func TestCheckerCheckSitemapWithMissingSitemap(t *testing.T) {
+	c, err := newChecker(missingMetadataURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	r, err := c.CheckSitemap(context.Background(), missingMetadataURL)
+	assert.Nil(t, err)
+	assert.True(t, r.OK())
+	assert.Equal(t, 1, len(r.Links()))
+	assert.Equal(t, []string{"sitemap not found"}, r.Links()[0].Warnings())
+}
+
+func TestCheckerCheckSitemapWithCanceledContext(t *testing.T) {
+	c, err := newChecker(sitemapCheckURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, err := c.CheckSitemap(ctx, sitemapCheckURL)
+	assert.Nil(t, err)
+
+	for _, l := range r.Links() {
+		assert.NotEqual(t, ErrorKindOther, l.ErrorKind())
+		assert.NotEqual(t, sitemapCheckURL+"/foo", l.URL())
+	}
+}
+
+func TestCheckerCheckSitemapWithSitemapError(t *testing.T) {
+	c, err := newChecker(missingMetadataURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	r, err := c.CheckSitemap(context.Background(), noResponseURL)
+	assert.Nil(t, err)
+	assert.Equal(t, noResponseURL+"/sitemap.xml", r.URL())
+	assert.Equal(t, 1, len(r.Links()))
+	assert.Equal(t, ErrorKindSitemap, r.Links()[0].ErrorKind())
+}
+
+func TestCheckerCheckSitemapError(t *testing.T) {
+	c, err := newChecker(missingMetadataURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	_, err = c.CheckSitemap(context.Background(), ":")
+	assert.NotNil(t, err)
+}
+
+func TestCheckerPages(t *testing.T) {
+	c, err := newChecker(rootURL, checkerOptions{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{rootURL + "/"}, c.Pages())
+}
+
+func TestLinkResultChannelToSlice(t *testing.T) {
+	foo, bar, baz := newLinkResult("foo", 200, nil), newLinkResult("bar", 200, nil), newLinkResult("baz", 200, nil)
+
 	for _, c := range []struct {
-		channel chan string
-		slice   []string
+		channel chan linkResult
+		slice   []linkResult
 	}{
 		{
-			make(chan string, 1),
-			[]string{},
+			make(chan linkResult, 1),
+			[]linkResult{},
 		},
 		{
-			func() chan string {
-				c := make(chan string, 1)
-				c <- "foo"
+			func() chan linkResult {
+				c := make(chan linkResult, 1)
+				c <- foo
 				return c
 			}(),
-			[]string{"foo"},
+			[]linkResult{foo},
 		},
 		{
-			func() chan string {
-				c := make(chan string, 2)
-				c <- "foo"
-				c <- "bar"
+			func() chan linkResult {
+				c := make(chan linkResult, 2)
+				c <- foo
+				c <- bar
 				return c
 			}(),
-			[]string{"foo", "bar"},
+			[]linkResult{foo, bar},
 		},
 		{
-			func() chan string {
-				c := make(chan string, 3)
-				c <- "foo"
-				c <- "bar"
-				c <- "baz"
+			func() chan linkResult {
+				c := make(chan linkResult, 3)
+				c <- foo
+				c <- bar
+				c <- baz
 				return c
 			}(),
-			[]string{"foo", "bar", "baz"},
+			[]linkResult{foo, bar, baz},
 		},
 	} {
-		assert.Equal(t, c.slice, stringChannelToSlice(c.channel))
+		assert.Equal(t, c.slice, linkResultChannelToSlice(c.channel))
+	}
+}
+
+func TestCheckerCheckFetchesPagesOnce(t *testing.T) {
+	ns := map[string]int{}
+	m := &sync.Mutex{}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		ns[r.URL.Path]++
+		m.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/foo" /><a href="/bar" />`))
+	}))
+	defer s.Close()
+
+	c, err := newChecker(s.URL, checkerOptions{})
+	assert.Nil(t, err)
+
+	go c.Check(context.Background())
+
+	for range c.Results() {
 	}
+
+	assert.Equal(t, map[string]int{"/": 1, "/foo": 1, "/bar": 1}, ns)
 }
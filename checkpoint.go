@@ -0,0 +1,98 @@
+package muffet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// checkpoint is a state of a check saved to resume it later.
+type checkpoint struct {
+	url     string
+	pages   []string
+	results []pageResult
+}
+
+func newCheckpoint(u string, ps []string, rs []pageResult) checkpoint {
+	return checkpoint{u, ps, rs}
+}
+
+func readCheckpoint(f string) (checkpoint, error) {
+	bs, err := ioutil.ReadFile(f)
+
+	if err != nil {
+		return checkpoint{}, err
+	}
+
+	c := checkpoint{}
+
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return checkpoint{}, err
+	}
+
+	return c, nil
+}
+
+func (c checkpoint) URL() string {
+	return c.url
+}
+
+// Pages returns URLs of pages found so far including pending ones.
+func (c checkpoint) Pages() []string {
+	return c.pages
+}
+
+func (c checkpoint) Results() []pageResult {
+	return c.results
+}
+
+// PendingPages returns URLs of pages found but not checked yet.
+func (c checkpoint) PendingPages() []string {
+	m := make(map[string]struct{}, len(c.results))
+
+	for _, r := range c.results {
+		m[r.URL()] = struct{}{}
+	}
+
+	ss := []string(nil)
+
+	for _, s := range c.pages {
+		if _, ok := m[s]; !ok {
+			ss = append(ss, s)
+		}
+	}
+
+	return ss
+}
+
+// Write writes a checkpoint into a file atomically.
+func (c checkpoint) Write(f string) error {
+	bs, err := json.Marshal(c)
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(f, bs)
+}
+
+type checkpointJSON struct {
+	URL     string       `json:"url"`
+	Pages   []string     `json:"pages"`
+	Results []pageResult `json:"results"`
+}
+
+func (c checkpoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(checkpointJSON{c.url, c.pages, c.results})
+}
+
+func (c *checkpoint) UnmarshalJSON(bs []byte) error {
+	j := checkpointJSON{}
+
+	if err := json.Unmarshal(bs, &j); err != nil {
+		return err
+	}
+
+	*c = newCheckpoint(j.URL, j.Pages, j.Results)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package muffet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointPendingPages(t *testing.T) {
+	c := newCheckpoint(rootURL, []string{"foo", "bar", "baz"}, []pageResult{newPageResult("bar", nil)})
+	assert.Equal(t, []string{"foo", "baz"}, c.PendingPages())
+}
+
+func TestCheckpointWrite(t *testing.T) {
+	d, err := ioutil.TempDir("", "muffet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	f := filepath.Join(d, "checkpoint.json")
+	c := newCheckpoint(
+		rootURL,
+		[]string{"foo", "bar"},
+		[]pageResult{
+			newPageResult("foo", []linkResult{
+				newLinkResult("bar", 200, nil),
+				newLinkResult("baz", 0, newHTTPStatusError(404)).Ignore(),
+			}),
+		})
+
+	assert.Nil(t, c.Write(f))
+
+	e, err := readCheckpoint(f)
+	assert.Nil(t, err)
+
+	assert.Equal(t, c.URL(), e.URL())
+	assert.Equal(t, c.Pages(), e.Pages())
+	assert.Equal(t, c.Results()[0].URL(), e.Results()[0].URL())
+	assert.Equal(t, c.Results()[0].Links()[0], e.Results()[0].Links()[0])
+	assert.Equal(t, ErrorKindHTTPStatus, e.Results()[0].Links()[1].ErrorKind())
+	assert.True(t, e.Results()[0].Links()[1].Ignored())
+
+	fs, err := ioutil.ReadDir(d)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(fs))
+}
+
+func TestCheckpointWriteError(t *testing.T) {
+	assert.NotNil(t, newCheckpoint(rootURL, nil, nil).Write("/nonexistent/checkpoint.json"))
+}
+
+func TestReadCheckpointError(t *testing.T) {
+	_, err := readCheckpoint("/nonexistent/checkpoint.json")
+	assert.NotNil(t, err)
+
+	f, err := ioutil.TempFile("", "muffet")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("{")
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	_, err = readCheckpoint(f.Name())
+	assert.NotNil(t, err)
+}
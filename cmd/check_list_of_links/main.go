@@ -1,9 +1,31 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"muffet"
 )
 
 func main() {
-	muffet.CheckListOfLinks(nil)
+	// doc-stage_usersys_redhat_com.crt
+	serve := flag.String("serve", "", "Directory to serve over http")
+	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
+	certFile := flag.String("cert-file", "", "Path to certificate file")
+	dialMode := flag.String("dial-mode", "dual", "Dial hosts over ipv4, ipv6, dual stacks or both of them")
+
+	flag.Parse()
+
+	err := muffet.CheckListOfLinks(os.Stdout, flag.Args(), muffet.DocCheckOptions{
+		ServedDirectory: *serve,
+		CertFile:        *certFile,
+		InsecureSSL:     *insecure,
+		DialMode:        *dialMode,
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
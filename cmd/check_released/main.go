@@ -1,9 +1,29 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"muffet"
 )
 
 func main() {
-	muffet.CheckReleased()
+	// doc-stage_usersys_redhat_com.crt
+	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
+	certFile := flag.String("cert-file", "", "Path to certificate file")
+	dialMode := flag.String("dial-mode", "dual", "Dial hosts over ipv4, ipv6, dual stacks or both of them")
+
+	flag.Parse()
+
+	err := muffet.CheckReleased(os.Stdout, flag.Args(), muffet.DocCheckOptions{
+		CertFile:    *certFile,
+		InsecureSSL: *insecure,
+		DialMode:    *dialMode,
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
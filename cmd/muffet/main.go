@@ -0,0 +1,9 @@
+package main
+
+import (
+	"muffet"
+)
+
+func main() {
+	muffet.Main()
+}
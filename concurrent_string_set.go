@@ -1,6 +1,9 @@
 package muffet
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 type concurrentStringSet struct {
 	set *sync.Map
@@ -14,3 +17,22 @@ func (c concurrentStringSet) Add(s string) bool {
 	_, exist := c.set.LoadOrStore(s, nil)
 	return exist
 }
+
+func (c concurrentStringSet) Contains(s string) bool {
+	_, ok := c.set.Load(s)
+	return ok
+}
+
+// Values returns sorted strings in a set.
+func (c concurrentStringSet) Values() []string {
+	ss := []string{}
+
+	c.set.Range(func(k, _ interface{}) bool {
+		ss = append(ss, k.(string))
+		return true
+	})
+
+	sort.Strings(ss)
+
+	return ss
+}
@@ -15,3 +15,12 @@ func TestConcurrentStringSetAdd(t *testing.T) {
 	assert.False(t, s.Add("foo"))
 	assert.True(t, s.Add("foo"))
 }
+
+func TestConcurrentStringSetValues(t *testing.T) {
+	s := newConcurrentStringSet()
+	assert.Equal(t, []string{}, s.Values())
+
+	s.Add("foo")
+	s.Add("bar")
+	assert.Equal(t, []string{"bar", "foo"}, s.Values())
+}
@@ -0,0 +1,186 @@
+package muffet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/html/charset"
+)
+
+// acceptedEncodings are content encodings decoded by decodeContent.
+const acceptedEncodings = "gzip, deflate, br"
+
+var errBodyTooLarge = errors.New("body too large")
+
+// responseBody returns a body of an HTML response decoded as per its content
+// encoding and converted into UTF-8. Bodies larger than a given size are
+// rejected.
+func responseBody(res *fasthttp.Response, n int) ([]byte, error) {
+	r, err := newResponseBodyReader(res, n)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, wrapBodyError(err, n)
+	}
+
+	return bs, nil
+}
+
+// newResponseBodyReader returns a reader of a body of an HTML response like
+// responseBody. A body streamed by a client is read from its connection as
+// the reader is read.
+func newResponseBodyReader(res *fasthttp.Response, n int) (io.Reader, error) {
+	r := res.BodyStream()
+
+	if r == nil {
+		r = bytes.NewReader(res.Body())
+	}
+
+	r, err := newContentReader(connectionReader{r}, string(res.Header.Peek("Content-Encoding")), n)
+
+	if err != nil {
+		return nil, wrapBodyError(err, n)
+	}
+
+	r, err = charset.NewReader(r, string(res.Header.Peek("Content-Type")))
+
+	if err == io.EOF {
+		return bytes.NewReader(nil), nil
+	} else if err != nil {
+		return nil, wrapBodyError(err, n)
+	}
+
+	return r, nil
+}
+
+// wrapBodyError converts an error on reading a body of a response with a
+// given size limit into a fetch error.
+func wrapBodyError(err error, n int) error {
+	if err == errBodyTooLarge {
+		return newBodyTooLargeError(n)
+	} else if e, ok := err.(fetchError); ok {
+		return e
+	}
+
+	return newFetchError(ErrorKindParse, err)
+}
+
+// decodeContent decodes a body encoded with content encodings listed in a
+// Content-Encoding header. It fails if a body is larger than a given size
+// before or after decoding.
+func decodeContent(bs []byte, h string, n int) ([]byte, error) {
+	r, err := newContentReader(bytes.NewReader(bs), h, n)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+// newContentReader returns a reader decoding a body encoded with content
+// encodings listed in a Content-Encoding header. Encodings are undone in the
+// reverse order. Each layer of a body is read up to a given size.
+func newContentReader(r io.Reader, h string, n int) (io.Reader, error) {
+	r = &sizeLimitedReader{r, n}
+	es := strings.Split(h, ",")
+
+	for i := len(es) - 1; i >= 0; i-- {
+		d, err := newContentDecoder(r, strings.ToLower(strings.TrimSpace(es[i])))
+
+		if err != nil {
+			return nil, err
+		} else if d == nil {
+			continue
+		}
+
+		r = &sizeLimitedReader{d, n}
+	}
+
+	return r, nil
+}
+
+// newContentDecoder returns a reader decoding a content encoding or nil for
+// the identity encoding.
+func newContentDecoder(r io.Reader, e string) (io.Reader, error) {
+	switch e {
+	case "", "identity":
+		return nil, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// Some servers send raw deflate streams without zlib headers.
+		b := bufio.NewReader(r)
+
+		if bs, err := b.Peek(2); err == nil && isZlibHeader(bs) {
+			return zlib.NewReader(b)
+		}
+
+		return flate.NewReader(b), nil
+	case "br":
+		return brotli.NewReader(r), nil
+	}
+
+	return nil, fmt.Errorf("unsupported content encoding: %v", e)
+}
+
+// isZlibHeader returns true if bytes are a zlib header of a deflate stream as
+// per RFC 1950.
+func isZlibHeader(bs []byte) bool {
+	return bs[0]&0x0f == 8 && (int(bs[0])<<8|int(bs[1]))%31 == 0
+}
+
+// sizeLimitedReader reads up to a size and fails with errBodyTooLarge if more
+// bytes remain.
+type sizeLimitedReader struct {
+	reader io.Reader
+	size   int
+}
+
+func (r *sizeLimitedReader) Read(bs []byte) (int, error) {
+	// One more byte is read to tell bodies as large as a limit from larger ones.
+	if len(bs) > r.size+1 {
+		bs = bs[:r.size+1]
+	}
+
+	n, err := r.reader.Read(bs)
+
+	if n > r.size {
+		n, r.size = r.size, 0
+		return n, errBodyTooLarge
+	}
+
+	r.size -= n
+
+	return n, err
+}
+
+// connectionReader classifies errors on reading bodies from connections.
+type connectionReader struct {
+	reader io.Reader
+}
+
+func (r connectionReader) Read(bs []byte) (int, error) {
+	n, err := r.reader.Read(bs)
+
+	if err != nil && err != io.EOF {
+		return n, wrapError(err)
+	}
+
+	return n, err
+}
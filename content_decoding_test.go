@@ -0,0 +1,151 @@
+package muffet
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func encodeContent(t *testing.T, bs []byte, f func(io.Writer) io.WriteCloser) []byte {
+	b := &bytes.Buffer{}
+	w := f(b)
+
+	_, err := w.Write(bs)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	return b.Bytes()
+}
+
+func TestDecodeContent(t *testing.T) {
+	bs := []byte("foo")
+	gz := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	z := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	fl := func(w io.Writer) io.WriteCloser {
+		f, err := flate.NewWriter(w, flate.DefaultCompression)
+		assert.Nil(t, err)
+		return f
+	}
+	br := func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }
+
+	for _, c := range []struct {
+		body     []byte
+		encoding string
+	}{
+		{bs, ""},
+		{bs, "identity"},
+		{encodeContent(t, bs, gz), "gzip"},
+		{encodeContent(t, bs, gz), "X-Gzip"},
+		{encodeContent(t, bs, z), "deflate"},
+		{encodeContent(t, bs, fl), "deflate"},
+		{encodeContent(t, bs, br), "br"},
+		{encodeContent(t, encodeContent(t, bs, gz), br), "gzip, br"},
+	} {
+		bs, err := decodeContent(c.body, c.encoding, 1024)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "foo", string(bs))
+	}
+}
+
+func TestDecodeContentError(t *testing.T) {
+	for _, e := range []string{"gzip", "compress"} {
+		_, err := decodeContent([]byte("foo"), e, 1024)
+		assert.NotNil(t, err)
+	}
+
+	_, err := decodeContent(encodeContent(t, []byte("foo"), func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}), "gzip", 2)
+	assert.Equal(t, errBodyTooLarge, err)
+}
+
+func TestResponseBody(t *testing.T) {
+	s, err := japanese.ShiftJIS.NewEncoder().String("日本語")
+	assert.Nil(t, err)
+
+	for _, c := range []struct {
+		body, contentType string
+	}{
+		{"<p>" + s + "</p>", "text/html; charset=Shift_JIS"},
+		{`<meta charset="shift_jis"><p>` + s + "</p>", "text/html"},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>` + s + "</p>", ""},
+	} {
+		res := fasthttp.Response{}
+		res.Header.SetNoDefaultContentType(true)
+		res.Header.SetContentType(c.contentType)
+		res.SetBodyString(c.body)
+		bs, err := responseBody(&res, 1024)
+
+		assert.Nil(t, err)
+		assert.Contains(t, string(bs), "<p>日本語</p>")
+	}
+
+	res := fasthttp.Response{}
+	res.Header.SetContentType("text/html")
+	res.SetBodyString("<p>日本語</p>")
+	bs, err := responseBody(&res, 1024)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>日本語</p>", string(bs))
+
+	res.ResetBody()
+	bs, err = responseBody(&res, 1024)
+
+	assert.Nil(t, err)
+	assert.Empty(t, bs)
+}
+
+func TestResponseBodyWithStream(t *testing.T) {
+	res := fasthttp.Response{}
+	res.Header.Set("Content-Encoding", "gzip")
+	res.SetBodyStream(bytes.NewReader(encodeContent(t, []byte("foo"), func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})), -1)
+	res.StreamBody = true
+	bs, err := responseBody(&res, 1024)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", string(bs))
+}
+
+func TestResponseBodyError(t *testing.T) {
+	res := fasthttp.Response{}
+	res.SetBodyString("foo")
+	_, err := responseBody(&res, 2)
+
+	assert.Equal(t, newBodyTooLargeError(2), err)
+
+	res.Header.Set("Content-Encoding", "gzip")
+	_, err = responseBody(&res, 1024)
+
+	assert.Equal(t, ErrorKindParse, errorKindOf(err))
+}
+
+func TestSizeLimitedReader(t *testing.T) {
+	for _, c := range []struct {
+		body string
+		err  error
+	}{
+		{"", nil},
+		{"fo", nil},
+		{"foo", nil},
+		{"foob", errBodyTooLarge},
+	} {
+		bs, err := ioutil.ReadAll(&sizeLimitedReader{iotest.OneByteReader(strings.NewReader(c.body)), 3})
+
+		assert.Equal(t, c.err, err)
+		assert.True(t, len(bs) <= 3)
+	}
+}
@@ -2,8 +2,27 @@ package muffet
 
 import "time"
 
+const version = "1.1.0"
+
 const (
-	defaultConcurrency     = 512
-	defaultMaxRedirections = 64
-	defaultTimeout         = 10 * time.Second
+	defaultConcurrency        = 512
+	defaultMaxRedirections    = 64
+	defaultTimeout            = 10 * time.Second
+	defaultListenAddress      = "127.0.0.1:8888"
+	defaultRobotsTxtUserAgent = "muffet"
+	defaultRequestProfile     = "default"
+	defaultMaxHTMLSize        = 32 << 20
+	defaultMaxBodySize        = 4 << 20
+	defaultDNSCacheTTL        = 60 * time.Second
+	defaultDialMode           = "dual"
+	happyEyeballsDelay        = 300 * time.Millisecond
+	defaultCheckpointInterval = 60 * time.Second
+	finishedJobTTL            = time.Hour
+	maxPagesInMemory          = 1 << 10
+	terminalProgressInterval  = 200 * time.Millisecond
+	logProgressInterval       = 10 * time.Second
 )
+
+var defaultSchemes = []string{"http", "https", "mailto", "tel"}
+
+var defaultRetriedErrorKinds = []ErrorKind{ErrorKindConnectionRefused, ErrorKindTimeout}
@@ -0,0 +1,212 @@
+package muffet
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// dialModes are modes of dialing hosts. In the "dual" mode, connections are
+// made as per Happy Eyeballs in RFC 8305. The "both" mode dials hosts in the
+// same way while fetchers also send requests over each address family.
+var dialModes = map[string]struct{}{
+	"ipv4": {},
+	"ipv6": {},
+	"dual": {},
+	"both": {},
+}
+
+// dialer dials TCP connections for HTTP clients resolving hosts with a shared
+// DNS cache. Addresses of hosts can be overridden like curl's --resolve.
+type dialer struct {
+	dnsCache  *dnsCache
+	overrides map[string]string
+	mode      string
+	timeout   time.Duration
+}
+
+func newDialer(c *dnsCache, os map[string]string, m string, t time.Duration) dialer {
+	return dialer{c, os, m, t}
+}
+
+// newDialFunc creates a function dialing hosts for HTTP clients with
+// initialized options.
+func newDialFunc(o fetcherOptions) fasthttp.DialFunc {
+	return newDialer(
+		newDNSCache(newResolver(o.DNSServer), o.DNSCacheTTL, o.Timeout),
+		o.Resolves,
+		o.DialMode,
+		o.Timeout,
+	).Dial
+}
+
+// newFamilyDialFuncs creates functions dialing hosts only over IPv4 and IPv6
+// respectively with a shared DNS cache.
+func newFamilyDialFuncs(o fetcherOptions) []fasthttp.DialFunc {
+	c := newDNSCache(newResolver(o.DNSServer), o.DNSCacheTTL, o.Timeout)
+
+	return []fasthttp.DialFunc{
+		newDialer(c, o.Resolves, "ipv4", o.Timeout).Dial,
+		newDialer(c, o.Resolves, "ipv6", o.Timeout).Dial,
+	}
+}
+
+// Dial dials an address of a host and a port.
+func (d dialer) Dial(a string) (net.Conn, error) {
+	h, p, err := net.SplitHostPort(a)
+
+	if err != nil {
+		return nil, err
+	}
+
+	as := []string(nil)
+
+	if s, ok := d.overrides[net.JoinHostPort(strings.ToLower(h), p)]; ok {
+		as = []string{s}
+	} else if as, err = d.dnsCache.LookupHost(h); err != nil {
+		return nil, err
+	}
+
+	if len(as) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: h, IsNotFound: true}
+	}
+
+	ps, fs := partitionAddresses(as)
+
+	switch d.mode {
+	case "ipv4", "ipv6":
+		if isIPv4(ps[0]) != (d.mode == "ipv4") {
+			ps = fs
+		}
+
+		if len(ps) == 0 {
+			return nil, &net.DNSError{
+				Err:        "no " + ipVersionName(d.mode == "ipv4") + " addresses",
+				Name:       h,
+				IsNotFound: true,
+			}
+		}
+
+		return d.dialSerial(ps, p)
+	}
+
+	if len(fs) == 0 {
+		return d.dialSerial(ps, p)
+	}
+
+	return d.dialParallel(ps, fs, p)
+}
+
+// dialSerial dials addresses in order until a connection is established.
+func (d dialer) dialSerial(as []string, p string) (net.Conn, error) {
+	err := error(nil)
+
+	for _, a := range as {
+		c, e := net.DialTimeout("tcp", net.JoinHostPort(a, p), d.timeout)
+
+		if e == nil {
+			return c, nil
+		}
+
+		err = e
+	}
+
+	return nil, err
+}
+
+type dialResult struct {
+	connection net.Conn
+	primary    bool
+	err        error
+}
+
+// dialParallel dials primary addresses and, after a delay or a failure of
+// them, fallback ones concurrently. The first connection established wins.
+func (d dialer) dialParallel(ps, fs []string, p string) (net.Conn, error) {
+	c := make(chan dialResult, 2)
+	dial := func(as []string, b bool) {
+		x, err := d.dialSerial(as, p)
+		c <- dialResult{x, b, err}
+	}
+
+	go dial(ps, true)
+
+	t := time.NewTimer(happyEyeballsDelay)
+	defer t.Stop()
+
+	n, f, err := 1, false, error(nil)
+
+	for n > 0 {
+		select {
+		case <-t.C:
+			if !f {
+				go dial(fs, false)
+				n, f = n+1, true
+			}
+		case r := <-c:
+			n--
+
+			if r.err == nil {
+				if n > 0 {
+					go closeDialResult(c)
+				}
+
+				return r.connection, nil
+			} else if err == nil || r.primary {
+				err = r.err
+			}
+
+			if !f {
+				go dial(fs, false)
+				n, f = n+1, true
+			}
+		}
+	}
+
+	return nil, err
+}
+
+func closeDialResult(c <-chan dialResult) {
+	if r := <-c; r.err == nil {
+		r.connection.Close()
+	}
+}
+
+func newAddressFamilyError(v4 bool, err error) fetchError {
+	return newFetchError(
+		ErrorKindAddressFamily,
+		fmt.Errorf("unreachable over %v: %v", ipVersionName(v4), err),
+	)
+}
+
+// partitionAddresses splits addresses into ones of the same family as the
+// first one and the others.
+func partitionAddresses(as []string) ([]string, []string) {
+	ps, fs := []string(nil), []string(nil)
+
+	for _, a := range as {
+		if isIPv4(a) == isIPv4(as[0]) {
+			ps = append(ps, a)
+		} else {
+			fs = append(fs, a)
+		}
+	}
+
+	return ps, fs
+}
+
+func isIPv4(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil
+}
+
+func ipVersionName(v4 bool) string {
+	if v4 {
+		return "IPv4"
+	}
+
+	return "IPv6"
+}
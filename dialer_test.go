@@ -0,0 +1,133 @@
+package muffet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDialer(m string, as ...string) dialer {
+	c := newDNSCache(net.DefaultResolver, time.Minute, time.Second)
+
+	if len(as) != 0 {
+		c.entries["foo.invalid"] = dnsCacheEntry{as, time.Now().Add(time.Minute)}
+	}
+
+	return newDialer(c, nil, m, time.Second)
+}
+
+// listenTCP listens on loopback addresses of given families at the same port
+// and returns the port.
+func listenTCP(t *testing.T, v4, v6 bool) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	_, p, err := net.SplitHostPort(l.Addr().String())
+	assert.Nil(t, err)
+
+	if !v4 {
+		assert.Nil(t, l.Close())
+	}
+
+	if !v6 {
+		return p, func() { l.Close() }
+	}
+
+	m, err := net.Listen("tcp", net.JoinHostPort("::1", p))
+	assert.Nil(t, err)
+
+	return p, func() {
+		l.Close()
+		m.Close()
+	}
+}
+
+func TestDialerDial(t *testing.T) {
+	c, err := newTestDialer(defaultDialMode).Dial("localhost:8080")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Close())
+}
+
+func TestDialerDialWithOverrides(t *testing.T) {
+	d := newDialer(
+		newDNSCache(net.DefaultResolver, time.Minute, time.Second),
+		map[string]string{"foo.invalid:8080": "127.0.0.1"},
+		defaultDialMode,
+		time.Second,
+	)
+
+	c, err := d.Dial("FOO.invalid:8080")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Close())
+
+	_, err = d.Dial("foo.invalid:8081")
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+}
+
+func TestDialerDialWithModes(t *testing.T) {
+	p, close := listenTCP(t, true, true)
+	defer close()
+
+	for _, x := range []struct {
+		mode    string
+		address string
+	}{
+		{"ipv4", "127.0.0.1"},
+		{"ipv6", "::1"},
+		{"dual", "::1"},
+		{"both", "::1"},
+	} {
+		c, err := newTestDialer(x.mode, "::1", "127.0.0.1").Dial("foo.invalid:" + p)
+		assert.Nil(t, err)
+
+		h, _, err := net.SplitHostPort(c.RemoteAddr().String())
+		assert.Nil(t, err)
+		assert.Equal(t, x.address, h)
+		assert.Nil(t, c.Close())
+	}
+}
+
+func TestDialerDialWithFallback(t *testing.T) {
+	p, close := listenTCP(t, true, false)
+	defer close()
+
+	c, err := newTestDialer("dual", "::1", "127.0.0.1").Dial("foo.invalid:" + p)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:"+p, c.RemoteAddr().String())
+	assert.Nil(t, c.Close())
+}
+
+func TestDialerDialWithMissingAddressFamily(t *testing.T) {
+	p, close := listenTCP(t, true, false)
+	defer close()
+
+	_, err := newTestDialer("ipv6", "127.0.0.1").Dial("foo.invalid:" + p)
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+
+	c, err := newTestDialer("both", "127.0.0.1").Dial("foo.invalid:" + p)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Close())
+}
+
+func TestDialerDialError(t *testing.T) {
+	_, err := newTestDialer(defaultDialMode).Dial("localhost")
+	assert.NotNil(t, err)
+
+	_, err = newTestDialer(defaultDialMode).Dial("foo.invalid:80")
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+
+	p, close := listenTCP(t, false, false)
+	close()
+
+	_, err = newTestDialer("both", "::1", "127.0.0.1").Dial("foo.invalid:" + p)
+	assert.Equal(t, ErrorKindConnectionRefused, errorKindOf(err))
+}
+
+func TestPartitionAddresses(t *testing.T) {
+	ps, fs := partitionAddresses([]string{"::1", "127.0.0.1", "::2", "127.0.0.2"})
+
+	assert.Equal(t, []string{"::1", "::2"}, ps)
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2"}, fs)
+}
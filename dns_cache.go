@@ -0,0 +1,61 @@
+package muffet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+type dnsCacheEntry struct {
+	addresses []string
+	expiry    time.Time
+}
+
+// dnsCache caches addresses of hosts for a TTL. Resolvers in the standard
+// library do not expose TTLs of records, so the same one is used for all
+// hosts.
+type dnsCache struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+	timeout  time.Duration
+	mutex    *sync.Mutex
+	entries  map[string]dnsCacheEntry
+}
+
+func newDNSCache(r *net.Resolver, ttl, timeout time.Duration) *dnsCache {
+	return &dnsCache{r, ttl, timeout, &sync.Mutex{}, map[string]dnsCacheEntry{}}
+}
+
+// LookupHost returns addresses of a host. Failed lookups are not cached.
+func (c *dnsCache) LookupHost(h string) ([]string, error) {
+	if net.ParseIP(h) != nil {
+		return []string{h}, nil
+	}
+
+	c.mutex.Lock()
+	e, ok := c.entries[h]
+	c.mutex.Unlock()
+
+	if ok && time.Now().Before(e.expiry) {
+		return e.addresses, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	as, err := c.resolver.LookupHost(ctx, h)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A zero TTL disables caching.
+	if c.ttl > 0 {
+		c.mutex.Lock()
+		c.entries[h] = dnsCacheEntry{as, time.Now().Add(c.ttl)}
+		c.mutex.Unlock()
+	}
+
+	return as, nil
+}
@@ -0,0 +1,65 @@
+package muffet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSCacheLookupHost(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver, time.Minute, time.Second)
+
+	as, err := c.LookupHost("localhost")
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, len(as))
+
+	e, ok := c.entries["localhost"]
+	assert.True(t, ok)
+	assert.Equal(t, as, e.addresses)
+
+	c.entries["localhost"] = dnsCacheEntry{[]string{"192.0.2.1"}, e.expiry}
+
+	as, err = c.LookupHost("localhost")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"192.0.2.1"}, as)
+}
+
+func TestDNSCacheLookupHostWithZeroTTL(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver, 0, time.Second)
+
+	as, err := c.LookupHost("localhost")
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, len(as))
+	assert.Equal(t, 0, len(c.entries))
+}
+
+func TestDNSCacheLookupHostWithExpiredEntries(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver, time.Minute, time.Second)
+	c.entries["localhost"] = dnsCacheEntry{[]string{"192.0.2.1"}, time.Now().Add(-time.Second)}
+
+	as, err := c.LookupHost("localhost")
+	assert.Nil(t, err)
+	assert.NotEqual(t, []string{"192.0.2.1"}, as)
+}
+
+func TestDNSCacheLookupHostWithIPAddresses(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver, time.Minute, time.Second)
+
+	for _, s := range []string{"127.0.0.1", "::1"} {
+		as, err := c.LookupHost(s)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{s}, as)
+	}
+
+	assert.Equal(t, 0, len(c.entries))
+}
+
+func TestDNSCacheLookupHostError(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver, time.Minute, time.Second)
+
+	_, err := c.LookupHost("foo.invalid")
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+	assert.Equal(t, 0, len(c.entries))
+}
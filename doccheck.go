@@ -8,6 +8,7 @@ import (
 	"golang.org/x/net/html"
 	"io"
 	"mime"
+	"sort"
 	"strings"
 )
 
@@ -19,32 +20,24 @@ func addSuite(fs Failures, url string) {
 	}
 }
 
-func addFailure(fs Failures, url, brokenUrl, error string) {
-	fs[url] = append(fs[url], []string{brokenUrl, error})
+func addFailure(fs Failures, url, brokenUrl string, kind ErrorKind, error string) {
+	fs[url] = append(fs[url], []string{brokenUrl, string(kind), error})
 }
 
-func printFailures(fs Failures) {
-	template := `
-<?xml version="1.0" encoding="UTF-8" ?> 
-   <testsuites id="20140612_170519" name="New_configuration (14/06/12 17:05:19)" tests="225" failures="1262" time="0.001">
-      <testsuite id="linkchecker" name="AMQ 7.3 documentation" tests="{{ tests }}" failures="{{ failures }}" time="0.001">
-         <testcase id="{{ url }}" name="{{ url }}" time="0.001">
-            <failure message="{{ brokenUrl }}" type="ERROR">
-WARNING: Use a program name that matches the source file name
-Category: COBOL Code Review – Naming Conventions
-File: /project/PROGRAM.cbl
-Line: 2
-      </failure>
-    </testcase>
-  </testsuite>
-</testsuites>
-`
-	_ = template
-}
+// printFailures writes broken links grouped by pages to a writer.
+func printFailures(w io.Writer, fs Failures) {
+	urls := make([]string, 0, len(fs))
+	for url := range fs {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	for _, url := range urls {
+		fmt.Fprintf(w, "%s\n", url)
 
-func mustNot(e error) {
-	if e != nil {
-		panic(e)
+		for _, f := range fs[url] {
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", f[0], f[1], f[2])
+		}
 	}
 }
 
@@ -52,9 +45,27 @@ func isSinglePageHtmlDocLink(s string) bool {
 	return strings.Contains(s, "/html-single/") || strings.Contains(s, "127.0.0.1:")
 }
 
-//^ERROR: 0, ([a-zA-Z:/\.#_?=0-9%&-]+) (.*)
+// WhitelistEntry is a known broken link matched by a suffix of its URL and a
+// kind of its error. An empty kind matches errors of any kind.
+type WhitelistEntry struct {
+	URLSuffix string
+	ErrorKind ErrorKind
+}
+
+// isWhitelisted returns true if an error on a link is known.
+func isWhitelisted(link string, err error) bool {
+	for _, e := range Whitelist {
+		if strings.HasSuffix(link, e.URLSuffix) && (e.ErrorKind == "" || e.ErrorKind == errorKindOf(err)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//^\*\*\t([a-zA-Z:/\.#_?=0-9%&-]+)\n\*\*\*\t([a-z0-9-]+): .*
 // {"\1", "\2"},
-var Whitelist = [][]string{
+var Whitelist = []WhitelistEntry{
 	// known issues
 
 	//{"https://access.redhat.com/documentation/en-us/red_hat_amq/7.3/html-single/using_amq_online_on_openshift_container_platform/#ref-example-roles-messaging", "id #ref-example-roles-messaging not found"},
@@ -205,24 +216,24 @@ var Whitelist = [][]string{
 	////{"https://v1-9.docs.kubernetes.io/docs/reference/generated/kubernetes-api/v1.9/#affinity-v1-core", "x509: certificate is valid for *.netlify.com, netlify.com, not v1-9.docs.kubernetes.io"},
 	////{"https://v1-9.docs.kubernetes.io/docs/reference/generated/kubernetes-api/v1.9/#ConfigMapKeySelector-v1-core", "x509: certificate is valid for *.netlify.com, netlify.com, not v1-9.docs.kubernetes.io"},
 
-	{"http://localhost:8161", "dial tcp4 127.0.0.1:8161: connect: connection refused"},
-	{"http://localhost:8161/console/login", "dial tcp4 127.0.0.1:8161: connect: connection refused"},
-	{"http://localhost:8161/jolokia", "dial tcp4 127.0.0.1:8161: connect: connection refused"},
+	{"http://localhost:8161", ErrorKindConnectionRefused},
+	{"http://localhost:8161/console/login", ErrorKindConnectionRefused},
+	{"http://localhost:8161/jolokia", ErrorKindConnectionRefused},
 	{"http://localhost:8161/jolokia/read/org.apache.activemq.artemis:module=Core,type=Server/Version", ""},
-	{"https://broker-amq-0.broker-amq-headless.amq-demo.svc", "lookup broker-amq-0.broker-amq-headless.amq-demo.svc: no such host"},
-	{"http://broker-amq-0.broker-amq-headless.amq-demo.svc", "lookup broker-amq-0.broker-amq-headless.amq-demo.svc: no such host"},
+	{"https://broker-amq-0.broker-amq-headless.amq-demo.svc", ErrorKindDNS},
+	{"http://broker-amq-0.broker-amq-headless.amq-demo.svc", ErrorKindDNS},
 	{"http://ocp.node.ip:ConsolePortNumber", ""},
 
-	{"http://kafka.apache.org/20/documentation.html#connectconfigs", "id #connectconfigs not found"},
-	{"http://kafka.apache.org/20/documentation.html#producerconfigs", "id #producerconfigs not found"},
-	{"http://kafka.apache.org/20/documentation.html#brokerconfigs", "id #brokerconfigs not found"},
-	{"http://kafka.apache.org/20/documentation.html#newconsumerconfigs", "id #newconsumerconfigs not found"},
+	{"http://kafka.apache.org/20/documentation.html#connectconfigs", ErrorKindMissingFragment},
+	{"http://kafka.apache.org/20/documentation.html#producerconfigs", ErrorKindMissingFragment},
+	{"http://kafka.apache.org/20/documentation.html#brokerconfigs", ErrorKindMissingFragment},
+	{"http://kafka.apache.org/20/documentation.html#newconsumerconfigs", ErrorKindMissingFragment},
 	// todo: url does not have /20/?
-	{"http://kafka.apache.org/documentation/#security_authz", "id #security_authz not found"},
-	{"http://kafka.apache.org/documentation/#brokerconfigs", "id #brokerconfigs not found"},
-	{"http://kafka.apache.org/documentation/#compaction", "id #connectconfigs not found"},
+	{"http://kafka.apache.org/documentation/#security_authz", ErrorKindMissingFragment},
+	{"http://kafka.apache.org/documentation/#brokerconfigs", ErrorKindMissingFragment},
+	{"http://kafka.apache.org/documentation/#compaction", ErrorKindMissingFragment},
 
-	{"https://access.redhat.com/containers/#/product/RedHatAmq", "id #/product/RedHatAmq not found"},
+	{"https://access.redhat.com/containers/#/product/RedHatAmq", ErrorKindMissingFragment},
 
 	//{"http://docs.oasis-open.org/amqp/core/v1.0/os/amqp-core-messaging-v1.0-os.html#section-message-format", "id #section-message-format not found"},
 	//{"http://docs.oasis-open.org/amqp/core/v1.0/os/amqp-core-messaging-v1.0-os.html#type-amqp-sequence", "id #type-amqp-sequence not found"},
@@ -258,14 +269,14 @@ var Whitelist = [][]string{
 	// https://access.redhat.com/containers/?product=Red%20Hat%20AMQ&application_categories_list=Messaging#/search/online
 	//{"https://access.redhat.com/containers/?/product=Red%20Hat%20AMQ&application_categories_list=Messaging#/search/online", "id #/search/online not found"},
 
-	{"https://access.redhat.com/labs/#?type=config", "id #?type=config not found"},
-	{"https://access.redhat.com/labs/#?type=deploy", "id #?type=deploy not found"},
-	{"https://access.redhat.com/labs/#?type=security", "id #?type=security not found"},
-	{"https://access.redhat.com/labs/#?type=troubleshoot", "id #?type=troubleshoot not found"},
-	{"https://access.redhat.com/management/subscriptions/#active", "id #active not found"},
-	{"https://access.redhat.com/security/security-updates/#/cve", "id #/cve not found"},
-	{"https://access.redhat.com/security/security-updates/#/security-advisories", "id #/security-advisories not found"},
-	{"https://access.redhat.com/security/security-updates/#/security-labs", "id #/security-labs not found"},
+	{"https://access.redhat.com/labs/#?type=config", ErrorKindMissingFragment},
+	{"https://access.redhat.com/labs/#?type=deploy", ErrorKindMissingFragment},
+	{"https://access.redhat.com/labs/#?type=security", ErrorKindMissingFragment},
+	{"https://access.redhat.com/labs/#?type=troubleshoot", ErrorKindMissingFragment},
+	{"https://access.redhat.com/management/subscriptions/#active", ErrorKindMissingFragment},
+	{"https://access.redhat.com/security/security-updates/#/cve", ErrorKindMissingFragment},
+	{"https://access.redhat.com/security/security-updates/#/security-advisories", ErrorKindMissingFragment},
+	{"https://access.redhat.com/security/security-updates/#/security-labs", ErrorKindMissingFragment},
 
 	//{"https://access.redhat.com/articles/3824851", "dialing to the given TCP address timed out"},
 
@@ -301,7 +312,7 @@ var Whitelist = [][]string{
 	{"https://access.stage.redhat.com/insights/info/?intcmp=mm|p|im|rhaijan2016&", ""},
 	{"https://access.stage.redhat.com/insights/info/?intcmp=mm|t|c1|rhaidec2015&", ""},
 	{"https://access.stage.redhat.com/security/security-updates/#/cve", ""},
-	{"https://access.stage.redhat.com/products/red-hat-certificate-system/", "timeout"},
+	{"https://access.stage.redhat.com/products/red-hat-certificate-system/", ErrorKindTimeout},
 	{"https://access.stage.redhat.com/management/subscriptions/#active", ""},
 
 	{"https://access.stage.redhat.com/changeLanguage?language=pt", ""},
@@ -321,9 +332,9 @@ var Whitelist = [][]string{
 	{"https://www.stage.redhat.com/wapps/ugc/register.html", ""},
 	{"https://access.stage.redhat.com/solution-engine", ""},
 
-	{"https://access.redhat.com/solution-engine", "404"},
+	{"https://access.redhat.com/solution-engine", ErrorKindHTTPStatus},
 
-	{"https://github.com/amqp/rhea#api", "404"},
+	{"https://github.com/amqp/rhea#api", ErrorKindHTTPStatus},
 	{"https://docs.google.com/presentation/d/1AV-qETM104Nuff43ryPR4hBqfY_knB6rF4ozDE_UXvw/edit#slide=id.gc80b71c4f_4_22", ""},
 
 	{"https://pantheon.cee.redhat.com/#/help", ""},
@@ -1,11 +1,14 @@
 package muffet
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/html"
 	"log"
+	"os"
 	"strings"
 	"testing"
 )
@@ -47,9 +50,34 @@ func TestMaine(t *testing.T) {
 	}
 }
 
+func TestPrintFailures(t *testing.T) {
+	fs := Failures{}
+	addFailure(fs, "https://foo.com", "https://foo.com/bar", ErrorKindHTTPStatus, "404")
+	addFailure(fs, "https://bar.com", "https://bar.com/foo", ErrorKindTimeout, "timeout")
+
+	b := &bytes.Buffer{}
+	printFailures(b, fs)
+
+	assert.Equal(
+		t,
+		"https://bar.com\n\thttps://bar.com/foo\ttimeout\ttimeout\nhttps://foo.com\n\thttps://foo.com/bar\thttp-status\t404\n",
+		b.String(),
+	)
+}
+
+func TestIsWhitelisted(t *testing.T) {
+	err := newFetchError(ErrorKindConnectionRefused, errors.New("connection refused"))
+
+	assert.True(t, isWhitelisted("http://localhost:8161/console/login", err))
+	assert.False(t, isWhitelisted("http://localhost:8161/console/login", newHTTPStatusError(404)))
+	assert.True(t, isWhitelisted("https://github.com/rh-messaging/amq-docs", newHTTPStatusError(404)))
+	assert.False(t, isWhitelisted("https://foo.com", err))
+}
+
 func TestLocalFilesCheck(t *testing.T) {
 	path := "/home/jdanek/repos/docs/amq-docs/build/"
-	links := serveDirectory(path)
+	links, err := serveDirectory(path)
+	assert.Nil(t, err)
 
-	CheckListOfLinks(links)
+	assert.Nil(t, CheckListOfLinks(os.Stdout, links, DocCheckOptions{}))
 }
@@ -0,0 +1,99 @@
+package muffet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind is a kind of an error on a link.
+type ErrorKind string
+
+// Kinds of errors on links
+const (
+	ErrorKindHTTPStatus        ErrorKind = "http-status"
+	ErrorKindDNS               ErrorKind = "dns"
+	ErrorKindConnectionRefused ErrorKind = "connection-refused"
+	ErrorKindTimeout           ErrorKind = "timeout"
+	ErrorKindTLS               ErrorKind = "tls"
+	ErrorKindAddressFamily     ErrorKind = "address-family"
+	ErrorKindRedirectLoop      ErrorKind = "redirect-loop"
+	ErrorKindTooManyRedirects  ErrorKind = "too-many-redirects"
+	ErrorKindMissingFragment   ErrorKind = "missing-fragment"
+	ErrorKindSoft404           ErrorKind = "soft-404"
+	ErrorKindSitemap           ErrorKind = "sitemap"
+	ErrorKindTooLarge          ErrorKind = "too-large"
+	ErrorKindParse             ErrorKind = "parse"
+	ErrorKindExcluded          ErrorKind = "excluded"
+	ErrorKindOther             ErrorKind = "other"
+)
+
+var errorKinds = []ErrorKind{
+	ErrorKindHTTPStatus,
+	ErrorKindDNS,
+	ErrorKindConnectionRefused,
+	ErrorKindTimeout,
+	ErrorKindTLS,
+	ErrorKindAddressFamily,
+	ErrorKindRedirectLoop,
+	ErrorKindTooManyRedirects,
+	ErrorKindMissingFragment,
+	ErrorKindSoft404,
+	ErrorKindSitemap,
+	ErrorKindTooLarge,
+	ErrorKindParse,
+	ErrorKindExcluded,
+	ErrorKindOther,
+}
+
+type errorKindSet map[ErrorKind]struct{}
+
+func newErrorKindSet(ks ...ErrorKind) errorKindSet {
+	s := make(errorKindSet, len(ks))
+
+	for _, k := range ks {
+		s[k] = struct{}{}
+	}
+
+	return s
+}
+
+func (s errorKindSet) Contains(k ErrorKind) bool {
+	_, ok := s[k]
+	return ok
+}
+
+func parseErrorKinds(ss []string) (errorKindSet, error) {
+	ks := make([]ErrorKind, 0, len(ss))
+
+	for _, s := range ss {
+		k, err := parseErrorKind(s)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ks = append(ks, k)
+	}
+
+	return newErrorKindSet(ks...), nil
+}
+
+func parseErrorKind(s string) (ErrorKind, error) {
+	for _, k := range errorKinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid error kind: %v", s)
+}
+
+func joinErrorKinds(ks []ErrorKind, sep string) string {
+	ss := make([]string, 0, len(ks))
+
+	for _, k := range ks {
+		ss = append(ss, string(k))
+	}
+
+	return strings.Join(ss, sep)
+}
@@ -0,0 +1,32 @@
+package muffet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKindSetContains(t *testing.T) {
+	s := newErrorKindSet(ErrorKindDNS, ErrorKindTimeout)
+
+	assert.True(t, s.Contains(ErrorKindDNS))
+	assert.True(t, s.Contains(ErrorKindTimeout))
+	assert.False(t, s.Contains(ErrorKindTLS))
+	assert.False(t, errorKindSet(nil).Contains(ErrorKindTLS))
+}
+
+func TestParseErrorKinds(t *testing.T) {
+	s, err := parseErrorKinds([]string{"dns", "http-status"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, newErrorKindSet(ErrorKindDNS, ErrorKindHTTPStatus), s)
+}
+
+func TestParseErrorKindsError(t *testing.T) {
+	_, err := parseErrorKinds([]string{"dns", "foo"})
+	assert.Equal(t, "invalid error kind: foo", err.Error())
+}
+
+func TestJoinErrorKinds(t *testing.T) {
+	assert.Equal(t, "dns, tls", joinErrorKinds([]ErrorKind{ErrorKindDNS, ErrorKindTLS}, ", "))
+}
@@ -0,0 +1,102 @@
+package muffet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/valyala/fasthttp"
+)
+
+type fetchError struct {
+	kind       ErrorKind
+	statusCode int
+	err        error
+}
+
+func newFetchError(k ErrorKind, err error) fetchError {
+	return fetchError{k, 0, err}
+}
+
+func newHTTPStatusError(s int) fetchError {
+	return fetchError{ErrorKindHTTPStatus, s, fmt.Errorf("%v", s)}
+}
+
+// wrapError classifies an error returned by a client or a parser. Errors which
+// are already classified are returned as they are.
+func wrapError(err error) fetchError {
+	if e, ok := err.(fetchError); ok {
+		return e
+	}
+
+	return newFetchError(classifyError(err), err)
+}
+
+// newBodyTooLargeError creates an error of a page larger than a limit.
+func newBodyTooLargeError(n int) fetchError {
+	return newFetchError(ErrorKindTooLarge, fmt.Errorf("page larger than %v bytes", n))
+}
+
+func (e fetchError) Error() string {
+	return e.err.Error()
+}
+
+func (e fetchError) Unwrap() error {
+	return e.err
+}
+
+func (e fetchError) Kind() ErrorKind {
+	return e.kind
+}
+
+func (e fetchError) StatusCode() int {
+	return e.statusCode
+}
+
+func errorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	if e, ok := err.(fetchError); ok {
+		return e.kind
+	}
+
+	return classifyError(err)
+}
+
+func classifyError(err error) ErrorKind {
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorKindConnectionRefused
+	case err == fasthttp.ErrTimeout, err == fasthttp.ErrDialTimeout,
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorKindTimeout
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.HasPrefix(err.Error(), "tls: "):
+		return ErrorKindTLS
+	case errors.As(err, &urlErr), err == mime.ErrInvalidMediaParameter,
+		strings.HasPrefix(err.Error(), "mime: "):
+		return ErrorKindParse
+	}
+
+	return ErrorKindOther
+}
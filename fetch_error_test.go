@@ -0,0 +1,53 @@
+package muffet
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewHTTPStatusError(t *testing.T) {
+	e := newHTTPStatusError(404)
+
+	assert.Equal(t, "404", e.Error())
+	assert.Equal(t, ErrorKindHTTPStatus, e.Kind())
+	assert.Equal(t, 404, e.StatusCode())
+}
+
+func TestWrapError(t *testing.T) {
+	e := newFetchError(ErrorKindTLS, errors.New("foo"))
+
+	assert.Equal(t, e, wrapError(e))
+	assert.Equal(t, ErrorKindDNS, wrapError(&net.DNSError{Err: "no such host", Name: "foo"}).Kind())
+}
+
+func TestErrorKindOf(t *testing.T) {
+	for _, c := range []struct {
+		error error
+		kind  ErrorKind
+	}{
+		{newHTTPStatusError(500), ErrorKindHTTPStatus},
+		{&net.DNSError{Err: "no such host", Name: "foo"}, ErrorKindDNS},
+		{
+			&net.OpError{Op: "dial", Net: "tcp4", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			ErrorKindConnectionRefused,
+		},
+		{fasthttp.ErrTimeout, ErrorKindTimeout},
+		{fasthttp.ErrDialTimeout, ErrorKindTimeout},
+		{x509.UnknownAuthorityError{}, ErrorKindTLS},
+		{x509.HostnameError{Certificate: &x509.Certificate{}, Host: "foo"}, ErrorKindTLS},
+		{errors.New("tls: handshake failure"), ErrorKindTLS},
+		{&url.Error{Op: "parse", URL: ":", Err: errors.New("missing protocol scheme")}, ErrorKindParse},
+		{errors.New("mime: no media type"), ErrorKindParse},
+		{errors.New("foo"), ErrorKindOther},
+	} {
+		assert.Equal(t, c.kind, errorKindOf(c.error))
+	}
+}
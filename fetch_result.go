@@ -3,10 +3,11 @@ package muffet
 type fetchResult struct {
 	statusCode int
 	page       *page
+	redirects  []redirect
 }
 
-func newFetchResult(s int, p *page) fetchResult {
-	return fetchResult{s, p}
+func newFetchResult(s int, p *page, rs []redirect) fetchResult {
+	return fetchResult{s, p, rs}
 }
 
 func (r fetchResult) StatusCode() int {
@@ -16,3 +17,17 @@ func (r fetchResult) StatusCode() int {
 func (r fetchResult) Page() (*page, bool) {
 	return r.page, r.page != nil
 }
+
+// Redirects returns redirections followed to get a result in order.
+func (r fetchResult) Redirects() []redirect {
+	return r.redirects
+}
+
+// Compact returns a result with only information needed by later lookups.
+func (r fetchResult) Compact() fetchResult {
+	if r.page != nil {
+		r.page = r.page.Compact()
+	}
+
+	return r
+}
@@ -7,31 +7,54 @@ import (
 )
 
 func TestNewFetchResult(t *testing.T) {
-	newFetchResult(200, nil)
+	newFetchResult(200, nil, nil)
 }
 
 func TestNewFetchResultWithPage(t *testing.T) {
-	p, err := newPage("", dummyHTML(t), newScraper(nil))
+	p, err := newPage("", nil, newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 
-	newFetchResult(200, p)
+	newFetchResult(200, p, nil)
 }
 
 func TestFetchResultStatusCode(t *testing.T) {
-	assert.Equal(t, 200, newFetchResult(200, nil).StatusCode())
+	assert.Equal(t, 200, newFetchResult(200, nil, nil).StatusCode())
 }
 
 func TestFetchResultPage(t *testing.T) {
-	p, ok := newFetchResult(200, nil).Page()
+	p, ok := newFetchResult(200, nil, nil).Page()
 
 	assert.False(t, ok)
 	assert.Equal(t, (*page)(nil), p)
 
-	q, err := newPage("", dummyHTML(t), newScraper(nil))
+	q, err := newPage("", nil, newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 
-	p, ok = newFetchResult(200, q).Page()
+	p, ok = newFetchResult(200, q, nil).Page()
 
 	assert.True(t, ok)
 	assert.Equal(t, q, p)
 }
+
+func TestFetchResultRedirects(t *testing.T) {
+	rs := []redirect{newRedirect("http://foo.com", 301, "https://foo.com")}
+
+	assert.Equal(t, rs, newFetchResult(200, nil, rs).Redirects())
+}
+
+func TestFetchResultCompact(t *testing.T) {
+	assert.Equal(t, newFetchResult(200, nil, nil), newFetchResult(200, nil, nil).Compact())
+
+	q, err := newPage("", nil, newScraper(nil, defaultSchemes))
+	assert.Nil(t, err)
+
+	rs := []redirect{newRedirect("http://foo.com", 301, "https://foo.com")}
+	r := newFetchResult(200, q, rs).Compact()
+	p, ok := r.Page()
+
+	assert.True(t, ok)
+	assert.True(t, p.Compacted())
+	assert.Equal(t, 200, r.StatusCode())
+	assert.Equal(t, rs, r.Redirects())
+	assert.False(t, q.Compacted())
+}
@@ -4,31 +4,64 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"mime"
+	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/html"
 )
 
 type fetcher struct {
-	client              *fasthttp.Client
+	client     *fasthttp.Client
+	htmlClient *fasthttp.Client
+	// familyClients are clients dialing hosts only over IPv4 and IPv6
+	// respectively in the "both" dial mode.
+	familyClients       []*fasthttp.Client
 	connectionSemaphore semaphore
 	cache               cache
-	options             fetcherOptions
+	// fetchedPages are URLs of pages whose links are got by this fetcher
+	// rather than others sharing a cache.
+	fetchedPages concurrentStringSet
+	options      fetcherOptions
 	scraper
+	schemeValidators map[string]schemeValidator
+	soft404Detector  soft404Detector
+	statistics       *statistics
+	metrics          *metrics
 }
 
 func newFetcher(c *fasthttp.Client, o fetcherOptions) fetcher {
 	o.Initialize()
 
+	d := c.Dial
+
+	if d == nil {
+		d = newDialFunc(o)
+	}
+
+	fcs := []*fasthttp.Client(nil)
+
+	if o.DialMode == "both" {
+		fcs = newFamilyClients(c, newFamilyDialFuncs(o))
+	}
+
 	return fetcher{
 		c,
+		c,
+		fcs,
 		newSemaphore(o.Concurrency),
 		newCache(),
+		newConcurrentStringSet(),
 		o,
-		newScraper(o.ExcludedPatterns),
+		newScraper(o.ExcludedPatterns, o.Schemes),
+		newSchemeValidators(o, d),
+		newSoft404Detector(o.Soft404TitlePatterns, o.Soft404BodyPatterns, o.Soft404Probe),
+		newStatistics(),
+		newMetrics(),
 	}
 }
 
@@ -36,41 +69,69 @@ func (f fetcher) Fetch(u string) (fetchResult, error) {
 	u, fr, err := separateFragment(u)
 
 	if err != nil {
-		return fetchResult{}, err
+		return fetchResult{}, newFetchError(ErrorKindParse, err)
 	}
 
 	r, err := f.sendRequestWithCache(u)
 
 	if err != nil {
-		return fetchResult{}, err
+		return r, err
 	}
 
 	if p, ok := r.Page(); ok && !f.options.IgnoreFragments && fr != "" {
-		if _, ok := p.IDs()[fr]; !ok {
-			return fetchResult{}, fmt.Errorf("id #%v not found", fr)
+		if !p.IDs().Contains(fr) {
+			// The page is kept so that it is checked even if it is found
+			// first by a link with a missing fragment.
+			err := newFetchError(ErrorKindMissingFragment, fmt.Errorf("id #%v not found", fr))
+			return newFetchResult(0, p, r.Redirects()), err
 		}
 	}
 
 	return r, nil
 }
 
+// fetchFailure is a cached error with redirections followed before it.
+type fetchFailure struct {
+	result fetchResult
+	err    error
+}
+
 func (f fetcher) sendRequestWithCache(u string) (fetchResult, error) {
 	x, s, ok := f.cache.LoadOrStore(u)
+	f.metrics.AddCacheLookup(ok)
 
 	if ok {
-		if err, ok := x.(error); ok {
-			return fetchResult{}, err
+		if e, ok := x.(fetchFailure); ok {
+			return e.result, e.err
 		}
 
 		return x.(fetchResult), nil
 	}
 
-	r, err := f.sendRequest(u)
+	r, err := f.sendRequestWithRetries(u)
 
+	// Only a caller which sends a request gets links of a page. Others get
+	// compacted pages.
 	if err == nil {
-		s(r)
+		if p, ok := r.Page(); ok {
+			f.fetchedPages.Add(p.URL().String())
+		}
+
+		s(r.Compact())
 	} else {
-		s(err)
+		s(fetchFailure{r, err})
+	}
+
+	return r, err
+}
+
+func (f fetcher) sendRequestWithRetries(u string) (fetchResult, error) {
+	r, err := f.sendRequest(u)
+
+	for i := 0; i < f.options.Retries && err != nil &&
+		f.options.RetriedErrorKinds.Contains(errorKindOf(err)); i++ {
+		f.metrics.AddRetry()
+		r, err = f.sendRequest(u)
 	}
 
 	return r, err
@@ -79,70 +140,310 @@ func (f fetcher) sendRequestWithCache(u string) (fetchResult, error) {
 func (f fetcher) sendRequest(u string) (fetchResult, error) {
 	f.connectionSemaphore.Request()
 	defer f.connectionSemaphore.Release()
+	defer f.metrics.ObserveFetch(time.Now())
+
+	if v, ok := f.schemeValidator(u); ok {
+		return fetchResult{}, v()
+	}
 
 	req, res := fasthttp.Request{}, fasthttp.Response{}
-	req.SetRequestURI(u)
-	req.SetConnectionClose()
+	defer res.CloseBodyStream()
+	rs, err := f.request(u, &req, &res)
+
+	if err != nil {
+		return newFetchResult(0, nil, rs), err
+	} else if err := f.checkAddressFamilies(req.URI().String(), res.StatusCode()); err != nil {
+		return newFetchResult(0, nil, rs), err
+	}
 
-	for k, v := range f.options.Headers {
-		req.Header.Add(k, v)
+	if ok, err := isHTML(&res); err != nil {
+		return newFetchResult(0, nil, rs), err
+	} else if !ok {
+		return newFetchResult(res.StatusCode(), nil, rs), nil
 	}
 
-	r := 0
+	r, err := newResponseBodyReader(&res, f.options.MaxHTMLSize)
 
-redirects:
-	for {
-		err := f.client.DoTimeout(&req, &res, f.options.Timeout)
+	if err != nil {
+		return newFetchResult(0, nil, rs), err
+	}
+
+	// Only soft 404 detection needs a whole tree of a page. Otherwise, a page
+	// is scanned as its body is read.
+	if f.soft404Detector.Enabled() {
+		bs, err := ioutil.ReadAll(r)
 
 		if err != nil {
-			return fetchResult{}, err
+			return newFetchResult(0, nil, rs), wrapBodyError(err, f.options.MaxHTMLSize)
 		}
 
+		r = bytes.NewReader(bs)
+		n, err := html.Parse(bytes.NewReader(bs))
+
+		if err != nil {
+			return newFetchResult(0, nil, rs), newFetchError(ErrorKindParse, err)
+		}
+
+		err = f.soft404Detector.Detect(req.URI().String(), n, len(rs) != 0, f.fetchDocument)
+
+		if err != nil {
+			return newFetchResult(0, nil, rs), wrapError(err)
+		}
+	}
+
+	p, err := readPage(req.URI().String(), r, f.scraper)
+
+	if err != nil {
+		return newFetchResult(0, nil, rs), wrapBodyError(err, f.options.MaxHTMLSize)
+	}
+
+	return newFetchResult(res.StatusCode(), p, rs), nil
+}
+
+// newFamilyClients creates clients with the same settings as a client except
+// for functions dialing hosts.
+func newFamilyClients(c *fasthttp.Client, ds []fasthttp.DialFunc) []*fasthttp.Client {
+	cs := make([]*fasthttp.Client, 0, len(ds))
+
+	for _, d := range ds {
+		cs = append(cs, &fasthttp.Client{
+			Dial:                d,
+			MaxConnsPerHost:     c.MaxConnsPerHost,
+			MaxResponseBodySize: c.MaxResponseBodySize,
+			TLSConfig:           c.TLSConfig,
+		})
+	}
+
+	return cs
+}
+
+// checkAddressFamilies sends a request at a final URL of a link over each
+// address family and fails if a host does not respond with the same status
+// code over one of them. Hosts without addresses of a family are skipped.
+func (f fetcher) checkAddressFamilies(u string, n int) error {
+	for i, c := range f.familyClients {
+		req, res := fasthttp.Request{}, fasthttp.Response{}
+		req.SetRequestURI(u)
+		req.SetConnectionClose()
+		replaceRequestHeaders(&req.Header, nil, f.options.RequestHeaders(string(req.URI().Host())))
+
+		err := c.DoTimeout(&req, &res, f.options.Timeout)
+
+		if e, ok := err.(*net.DNSError); ok && e.IsNotFound {
+			continue
+		} else if err != nil && err != fasthttp.ErrBodyTooLarge {
+			return newAddressFamilyError(i == 0, wrapError(err))
+		} else if res.StatusCode() != n {
+			return newAddressFamilyError(i == 0, newHTTPStatusError(res.StatusCode()))
+		}
+	}
+
+	return nil
+}
+
+// schemeValidator returns a function validating a URL if it is not fetched
+// over HTTP.
+func (f fetcher) schemeValidator(s string) (func() error, bool) {
+	u, err := url.Parse(s)
+
+	if err != nil || u.Scheme == "http" || u.Scheme == "https" {
+		return nil, false
+	}
+
+	v, ok := f.schemeValidators[u.Scheme]
+
+	if !ok {
+		return func() error {
+			return newFetchError(ErrorKindOther, fmt.Errorf("unsupported scheme: %v", u.Scheme))
+		}, true
+	}
+
+	return func() error { return v.Validate(u) }, true
+}
+
+// fetchDocument fetches an HTML page without caching or any checks on it.
+func (f fetcher) fetchDocument(u string) (document, error) {
+	req, res := fasthttp.Request{}, fasthttp.Response{}
+	defer res.CloseBodyStream()
+
+	if _, err := f.request(u, &req, &res); err != nil {
+		return document{}, err
+	}
+
+	if ok, err := isHTML(&res); err != nil {
+		return document{}, err
+	} else if !ok {
+		return document{}, errors.New("non-HTML page")
+	}
+
+	bs, err := responseBody(&res, f.options.MaxHTMLSize)
+
+	if err != nil {
+		return document{}, err
+	}
+
+	n, err := html.Parse(bytes.NewReader(bs))
+
+	if err != nil {
+		return document{}, newFetchError(ErrorKindParse, err)
+	}
+
+	return newDocument(req.URI().String(), n), nil
+}
+
+// request sends a request following redirections and returns them. They are
+// returned even on errors.
+func (f fetcher) request(u string, req *fasthttp.Request, res *fasthttp.Response) ([]redirect, error) {
+	req.SetRequestURI(u)
+	req.SetConnectionClose()
+
+	rs, hs := []redirect(nil), map[string]string(nil)
+
+	for {
+		h := string(req.URI().Host())
+		hs = replaceRequestHeaders(&req.Header, hs, f.options.RequestHeaders(h))
+		done := f.statistics.StartRequest(h)
+		err := f.client.DoTimeout(req, res, f.options.Timeout)
+
+		// Bodies without their lengths are not streamed until connections are
+		// closed. HTML pages are requested again as they can be larger than
+		// other responses.
+		if err == fasthttp.ErrBodyTooLarge && res.StatusCode()/100 == 2 && f.htmlClient != f.client {
+			if ok, _ := isHTML(res); ok {
+				err = f.htmlClient.DoTimeout(req, res, f.options.Timeout)
+			}
+		}
+
+		done()
+
+		// Headers are read before a body is found too large.
+		l := err == fasthttp.ErrBodyTooLarge
+
+		if err != nil && !l {
+			f.metrics.AddRequest(h, 0)
+			return rs, wrapError(err)
+		}
+
+		f.metrics.AddRequest(h, res.StatusCode())
+
 		switch res.StatusCode() / 100 {
 		case 2:
-			break redirects
+			return rs, f.checkBodySize(res, l)
 		case 3:
-			r++
+			// Bodies of redirections are never read.
+			res.ResetBody()
+			bs := res.Header.Peek("Location")
 
-			if r > f.options.MaxRedirections {
-				return fetchResult{}, errors.New("too many redirections")
+			if len(bs) == 0 {
+				return rs, newFetchError(ErrorKindOther, errors.New("location header not found"))
 			}
 
-			bs := res.Header.Peek("Location")
+			v := req.URI().String()
+			l, err := resolveLocation(v, string(bs))
 
-			if len(bs) == 0 {
-				return fetchResult{}, errors.New("location header not found")
+			if err != nil {
+				return rs, newFetchError(ErrorKindParse, err)
+			}
+
+			req.URI().Update(l)
+			rs = append(rs, newRedirect(v, res.StatusCode(), req.URI().String()))
+
+			if err := checkRedirectLoop(rs); err != nil {
+				return rs, err
 			}
 
-			req.URI().UpdateBytes(bs)
+			if len(rs) > f.options.MaxRedirections {
+				return rs, newFetchError(ErrorKindTooManyRedirects, errors.New("too many redirections"))
+			}
 		default:
-			return fetchResult{}, fmt.Errorf("%v", res.StatusCode())
+			return rs, newHTTPStatusError(res.StatusCode())
 		}
 	}
+}
 
-	if s := strings.TrimSpace(string(res.Header.Peek("Content-Type"))); s != "" {
-		t, _, err := mime.ParseMediaType(s)
+// checkBodySize checks a response against a limit for its content type once
+// its headers arrive. Bodies of non-HTML responses are dropped without being
+// read as they are never used, so they are never too large. Bodies of HTML
+// ones are checked further as they are read.
+func (f fetcher) checkBodySize(res *fasthttp.Response, large bool) error {
+	if ok, err := isHTML(res); err != nil || !ok {
+		res.ResetBody()
+		return nil
+	} else if large {
+		return newBodyTooLargeError(f.options.MaxHTMLSize)
+	}
 
-		if err != nil {
-			return fetchResult{}, err
-		} else if t != "text/html" {
-			return newFetchResult(res.StatusCode(), nil), nil
-		}
+	return nil
+}
+
+// replaceRequestHeaders replaces old headers in a request with new ones so
+// that headers for a host are not sent to others on redirections.
+func replaceRequestHeaders(h *fasthttp.RequestHeader, old, hs map[string]string) map[string]string {
+	for k := range old {
+		h.Del(k)
+	}
+
+	for k, v := range hs {
+		h.Set(k, v)
+	}
+
+	return hs
+}
+
+func isHTML(res *fasthttp.Response) (bool, error) {
+	s := strings.TrimSpace(string(res.Header.Peek("Content-Type")))
+
+	if s == "" {
+		return true, nil
 	}
 
-	n, err := html.Parse(bytes.NewReader(res.Body()))
+	t, _, err := mime.ParseMediaType(s)
 
 	if err != nil {
-		return fetchResult{}, err
+		return false, newFetchError(ErrorKindParse, err)
 	}
 
-	p, err := newPage(req.URI().String(), n, f.scraper)
+	return t == "text/html", nil
+}
+
+// resolveLocation resolves a Location header value against a request URL.
+// Relative and protocol-relative values are allowed as per RFC 7231.
+func resolveLocation(u, l string) (string, error) {
+	b, err := url.Parse(u)
+
+	if err != nil {
+		return "", err
+	}
+
+	v, err := url.Parse(l)
 
 	if err != nil {
-		return fetchResult{}, err
+		return "", err
+	}
+
+	return b.ResolveReference(v).String(), nil
+}
+
+func checkRedirectLoop(rs []redirect) error {
+	l := rs[len(rs)-1].Location()
+
+	for i, r := range rs {
+		if r.URL() != l {
+			continue
+		}
+
+		ss := make([]string, 0, len(rs)-i+1)
+
+		for _, r := range rs[i:] {
+			ss = append(ss, r.URL())
+		}
+
+		return newFetchError(
+			ErrorKindRedirectLoop,
+			fmt.Errorf("redirect loop: %v", strings.Join(append(ss, l), " -> ")))
 	}
 
-	return newFetchResult(res.StatusCode(), p), nil
+	return nil
 }
 
 func separateFragment(s string) (string, string, error) {
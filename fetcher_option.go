@@ -1,18 +1,47 @@
 package muffet
 
 import (
+	"net/url"
 	"regexp"
 	"time"
 )
 
 type fetcherOptions struct {
-	Concurrency      int
-	ExcludedPatterns []*regexp.Regexp
-	Headers          map[string]string
-	IgnoreFragments  bool
-	MaxRedirections  int
-	Timeout          time.Duration
-	OnePageOnly      bool
+	Concurrency       int
+	ExcludedPatterns  []*regexp.Regexp
+	Headers           map[string]string
+	IgnoreFragments   bool
+	MaxRedirections   int
+	Timeout           time.Duration
+	OnePageOnly       bool
+	Retries           int
+	RetriedErrorKinds errorKindSet
+	Soft404TitlePatterns,
+	Soft404BodyPatterns []soft404Pattern
+	Soft404Probe bool
+	// Profile is a name of a request profile.
+	Profile   string
+	UserAgent string
+	// HostHeaders are custom headers sent only to hosts.
+	HostHeaders map[string]map[string]string
+	// MaxHTMLSize is a maximum size of HTML pages and MaxBodySize is one of
+	// other responses in bytes.
+	MaxHTMLSize,
+	MaxBodySize int
+	// Schemes are schemes of links checked.
+	Schemes []string
+	// CheckMX makes a fetcher check MX records of domains in mailto links.
+	CheckMX bool
+	// DNSServer is an address of a DNS server used instead of the system one.
+	DNSServer string
+	// DNSCacheTTL is a duration for which addresses of hosts are cached.
+	DNSCacheTTL time.Duration
+	// DisableDNSCache makes a fetcher look up addresses of hosts every time.
+	DisableDNSCache bool
+	// Resolves are addresses used for pairs of hosts and ports.
+	Resolves map[string]string
+	// DialMode is a mode of dialing hosts over IPv4 and IPv6.
+	DialMode string
 }
 
 func (o *fetcherOptions) Initialize() {
@@ -27,4 +56,53 @@ func (o *fetcherOptions) Initialize() {
 	if o.Timeout <= 0 {
 		o.Timeout = defaultTimeout
 	}
+
+	if o.RetriedErrorKinds == nil {
+		o.RetriedErrorKinds = newErrorKindSet(defaultRetriedErrorKinds...)
+	}
+
+	if o.Profile == "" {
+		o.Profile = defaultRequestProfile
+	}
+
+	if o.MaxHTMLSize <= 0 {
+		o.MaxHTMLSize = defaultMaxHTMLSize
+	}
+
+	if o.MaxBodySize <= 0 {
+		o.MaxBodySize = defaultMaxBodySize
+	}
+
+	// A zero TTL disables a DNS cache after initialization.
+	if o.DisableDNSCache {
+		o.DNSCacheTTL = 0
+	} else if o.DNSCacheTTL <= 0 {
+		o.DNSCacheTTL = defaultDNSCacheTTL
+	}
+
+	if o.DialMode == "" {
+		o.DialMode = defaultDialMode
+	}
+
+	if o.Schemes == nil {
+		o.Schemes = defaultSchemes
+	}
+}
+
+// RequestHeaders returns headers sent to a host. Custom headers for the host
+// take precedence over common ones, a user agent and ones in a profile in
+// order.
+func (o fetcherOptions) RequestHeaders(h string) map[string]string {
+	ua := map[string]string(nil)
+
+	if o.UserAgent != "" {
+		ua = map[string]string{"User-Agent": o.UserAgent}
+	}
+
+	return mergeHeaders(
+		requestProfiles[o.Profile],
+		ua,
+		o.Headers,
+		o.HostHeaders[(&url.URL{Host: h}).Hostname()],
+	)
 }
@@ -2,6 +2,7 @@ package muffet
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,4 +14,49 @@ func TestFetcherOptionsInitialize(t *testing.T) {
 	assert.Equal(t, defaultConcurrency, o.Concurrency)
 	assert.Equal(t, defaultMaxRedirections, o.MaxRedirections)
 	assert.Equal(t, defaultTimeout, o.Timeout)
+	assert.Equal(t, newErrorKindSet(defaultRetriedErrorKinds...), o.RetriedErrorKinds)
+	assert.Equal(t, defaultRequestProfile, o.Profile)
+	assert.Equal(t, defaultMaxHTMLSize, o.MaxHTMLSize)
+	assert.Equal(t, defaultMaxBodySize, o.MaxBodySize)
+}
+
+func TestFetcherOptionsInitializeDNSCacheTTL(t *testing.T) {
+	for _, x := range [][2]time.Duration{
+		{0, defaultDNSCacheTTL},
+		{-1, defaultDNSCacheTTL},
+		{time.Second, time.Second},
+	} {
+		o := fetcherOptions{DNSCacheTTL: x[0]}
+		o.Initialize()
+
+		assert.Equal(t, x[1], o.DNSCacheTTL)
+	}
+}
+
+func TestFetcherOptionsInitializeDisableDNSCache(t *testing.T) {
+	o := fetcherOptions{DNSCacheTTL: time.Second, DisableDNSCache: true}
+	o.Initialize()
+
+	assert.Equal(t, time.Duration(0), o.DNSCacheTTL)
+}
+
+func TestFetcherOptionsRequestHeaders(t *testing.T) {
+	o := fetcherOptions{
+		Profile:   "browser",
+		UserAgent: "foo",
+		Headers:   map[string]string{"accept-language": "ja", "X-Foo": "foo"},
+		HostHeaders: map[string]map[string]string{
+			"foo.com": {"X-Foo": "bar"},
+		},
+	}
+
+	hs := o.RequestHeaders("foo.com:443")
+
+	assert.Equal(t, "foo", hs["User-Agent"])
+	assert.Equal(t, "ja", hs["Accept-Language"])
+	assert.Equal(t, "bar", hs["X-Foo"])
+	assert.Equal(t, acceptedEncodings, hs["Accept-Encoding"])
+
+	assert.Equal(t, "foo", o.RequestHeaders("bar.com")["X-Foo"])
+	assert.Equal(t, map[string]string{}, fetcherOptions{}.RequestHeaders("foo.com"))
 }
@@ -2,7 +2,14 @@ package muffet
 
 import (
 	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,8 +40,9 @@ func TestFetcherFetchCache(t *testing.T) {
 	r, err := f.Fetch(rootURL)
 	assert.NotEqual(t, fetchResult{}, r)
 	assert.Nil(t, err)
-	_, ok := r.Page()
+	p, ok := r.Page()
 	assert.True(t, ok)
+	assert.False(t, p.Compacted())
 
 	_, err = f.Fetch(nonExistentURL)
 	assert.NotNil(t, err)
@@ -42,11 +50,18 @@ func TestFetcherFetchCache(t *testing.T) {
 	r, err = f.Fetch(rootURL)
 	assert.NotEqual(t, fetchResult{}, r)
 	assert.Nil(t, err)
-	_, ok = r.Page()
+	p, ok = r.Page()
 	assert.True(t, ok)
+	assert.True(t, p.Compacted())
 
 	_, err = f.Fetch(nonExistentURL)
 	assert.NotNil(t, err)
+
+	assert.Equal(t, 2, f.metrics.cacheHits)
+	assert.Equal(t, 2, f.metrics.cacheMisses)
+	assert.Equal(t, 1, f.metrics.requests[requestMetricKey{"localhost:8080", "2xx"}])
+	assert.Equal(t, 1, f.metrics.requests[requestMetricKey{"localhost:8080", "4xx"}])
+	assert.Equal(t, 2, f.metrics.fetchDurations.count)
 }
 
 func TestFetcherFetchCacheConcurrency(t *testing.T) {
@@ -79,6 +94,120 @@ func TestFetcherFetchWithFragments(t *testing.T) {
 
 	_, err = f.Fetch(nonExistentIDURL)
 	assert.Equal(t, "id #bar not found", err.Error())
+	assert.Equal(t, ErrorKindMissingFragment, errorKindOf(err))
+}
+
+func TestFetcherFetchWithRequestProfile(t *testing.T) {
+	_, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(browserOnlyURL)
+	assert.Equal(t, ErrorKindHTTPStatus, errorKindOf(err))
+
+	for _, o := range []fetcherOptions{{Profile: "browser"}, {UserAgent: "Mozilla/5.0"}} {
+		r, err := newFetcher(&fasthttp.Client{}, o).Fetch(browserOnlyURL + "#foo")
+		assert.Nil(t, err)
+
+		_, ok := r.Page()
+		assert.True(t, ok)
+	}
+}
+
+func TestFetcherFetchWithCharset(t *testing.T) {
+	r, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(shiftJISURL + "#日本語")
+	assert.Nil(t, err)
+
+	p, ok := r.Page()
+	assert.True(t, ok)
+	assert.True(t, p.IDs().Contains("日本語"))
+}
+
+func TestFetcherFetchWithLargeBodies(t *testing.T) {
+	o := fetcherOptions{MaxHTMLSize: 1024, MaxBodySize: 1024}
+
+	for _, c := range []*fasthttp.Client{
+		{},
+		{MaxResponseBodySize: 1024},
+		{MaxResponseBodySize: 1024, StreamResponseBody: true},
+	} {
+		f := newFetcher(c, o)
+
+		_, err := f.Fetch(largePageURL)
+		assert.Equal(t, ErrorKindTooLarge, errorKindOf(err))
+		assert.Equal(t, "page larger than 1024 bytes", err.Error())
+
+		r, err := f.Fetch(largeFileURL)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, r.StatusCode())
+
+		_, ok := r.Page()
+		assert.False(t, ok)
+	}
+}
+
+func TestFetcherFetchWithLargeBodiesAndSmallBodyLimit(t *testing.T) {
+	o := fetcherOptions{MaxHTMLSize: 1 << 20, MaxBodySize: 1024}
+
+	// HTML pages are requested again only if their bodies are not streamed.
+	for _, c := range []struct {
+		stream bool
+		dials  int32
+	}{
+		{false, 1},
+		{true, 0},
+	} {
+		n := int32(0)
+
+		f := newFetcher(&fasthttp.Client{MaxResponseBodySize: o.MaxBodySize, StreamResponseBody: c.stream}, o)
+		f.htmlClient = &fasthttp.Client{
+			Dial: func(a string) (net.Conn, error) {
+				atomic.AddInt32(&n, 1)
+				return fasthttp.Dial(a)
+			},
+			MaxResponseBodySize: o.MaxHTMLSize,
+		}
+
+		r, err := f.Fetch(largeFileURL)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, r.StatusCode())
+		assert.Equal(t, int32(0), atomic.LoadInt32(&n))
+
+		r, err = f.Fetch(largePageURL)
+		assert.Nil(t, err)
+		assert.Equal(t, c.dials, atomic.LoadInt32(&n))
+
+		p, ok := r.Page()
+		assert.True(t, ok)
+		assert.True(t, p.IDs().Contains("end"))
+	}
+}
+
+func TestFetcherFetchWithHostHeaders(t *testing.T) {
+	hs := map[string]string{
+		"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("me:password")),
+	}
+
+	_, err := newFetcher(
+		&fasthttp.Client{},
+		fetcherOptions{HostHeaders: map[string]map[string]string{"localhost": hs}},
+	).Fetch(basicAuthURL)
+	assert.Nil(t, err)
+
+	_, err = newFetcher(
+		&fasthttp.Client{},
+		fetcherOptions{HostHeaders: map[string]map[string]string{"foo.com": hs}},
+	).Fetch(basicAuthURL)
+	assert.Equal(t, ErrorKindHTTPStatus, errorKindOf(err))
+}
+
+func TestFetcherFetchWithSchemes(t *testing.T) {
+	f := newFetcher(&fasthttp.Client{}, fetcherOptions{})
+
+	_, err := f.Fetch("mailto:me@right.here")
+	assert.Nil(t, err)
+
+	_, err = f.Fetch("tel:foo")
+	assert.Equal(t, ErrorKindParse, errorKindOf(err))
+
+	_, err = f.Fetch("ftp://localhost/foo")
+	assert.Equal(t, "unsupported scheme: ftp", err.Error())
 }
 
 func TestFetcherFetchIgnoreFragments(t *testing.T) {
@@ -104,18 +233,227 @@ func TestFetcherFetchWithTLSVerification(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestFetcherFetchWithRedirections(t *testing.T) {
+	r, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(permanentRedirectURL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []redirect{
+		newRedirect(permanentRedirectURL, 301, redirectURL),
+		newRedirect(redirectURL, 300, rootURL+"/"),
+	}, r.Redirects())
+}
+
+func TestFetcherFetchWithRelativeRedirections(t *testing.T) {
+	for _, c := range [][2]string{
+		{relativeRedirectURL, existentURL},
+		{protocolRelativeRedirectURL, missingMetadataURL + "/"},
+	} {
+		r, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(c[0])
+
+		assert.Nil(t, err)
+		assert.Equal(t, []redirect{newRedirect(c[0], 302, c[1])}, r.Redirects())
+	}
+}
+
 func TestFetcherFetchWithInfiniteRedirections(t *testing.T) {
 	_, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(infiniteRedirectURL)
 	assert.NotNil(t, err)
+	assert.Equal(t, ErrorKindRedirectLoop, errorKindOf(err))
+}
+
+func TestFetcherFetchWithRedirectLoop(t *testing.T) {
+	r, err := newFetcher(&fasthttp.Client{}, fetcherOptions{}).Fetch(redirectLoopURL)
+
+	assert.Equal(t, ErrorKindRedirectLoop, errorKindOf(err))
+	assert.Equal(
+		t,
+		"redirect loop: "+redirectLoopURL+" -> "+redirectLoopBackURL+" -> "+redirectLoopURL,
+		err.Error())
+	assert.Equal(t, 2, len(r.Redirects()))
+}
+
+func TestFetcherFetchWithTooManyRedirections(t *testing.T) {
+	r, err := newFetcher(&fasthttp.Client{}, fetcherOptions{MaxRedirections: 3}).Fetch(longRedirectURL)
+
+	assert.Equal(t, ErrorKindTooManyRedirects, errorKindOf(err))
+	assert.Equal(t, "too many redirections", err.Error())
+	assert.Equal(t, 4, len(r.Redirects()))
+}
+
+func TestFetcherFetchWithRedirectionsAndError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/foo":
+			w.Header().Set("Location", "/bar")
+			w.WriteHeader(302)
+		case "/bar":
+			w.WriteHeader(404)
+		case "/baz":
+			w.Header().Set("Location", "/qux")
+			w.WriteHeader(301)
+		case "/qux":
+			w.WriteHeader(302)
+		}
+	}))
+	defer s.Close()
+
+	f := newFetcher(&fasthttp.Client{}, fetcherOptions{})
+
+	for _, c := range []struct {
+		url, location string
+		status        int
+		kind          ErrorKind
+	}{
+		{s.URL + "/foo", s.URL + "/bar", 302, ErrorKindHTTPStatus},
+		{s.URL + "/baz", s.URL + "/qux", 301, ErrorKindOther},
+	} {
+		// The second fetches hit a cache.
+		for i := 0; i < 2; i++ {
+			r, err := f.Fetch(c.url)
+
+			assert.Equal(t, c.kind, errorKindOf(err))
+			assert.Equal(t, []redirect{newRedirect(c.url, c.status, c.location)}, r.Redirects())
+		}
+	}
+}
+
+func TestFetcherFetchWithSoft404Detection(t *testing.T) {
+	for _, o := range []fetcherOptions{
+		{Soft404Probe: true},
+		{Soft404TitlePatterns: []soft404Pattern{newSoft404Pattern("localhost", regexp.MustCompile("not found"))}},
+		{Soft404BodyPatterns: []soft404Pattern{newSoft404Pattern("*", regexp.MustCompile("was not found"))}},
+	} {
+		f := newFetcher(&fasthttp.Client{}, o)
+
+		for _, s := range []string{soft404URL, soft404URL + "/foo", soft404URL + "/moved"} {
+			_, err := f.Fetch(s)
+			assert.Nil(t, err)
+		}
+
+		_, err := f.Fetch(soft404URL + "/bar")
+		assert.Equal(t, ErrorKindSoft404, errorKindOf(err))
+	}
 }
 
 func TestFetcherFetchError(t *testing.T) {
 	f := newFetcher(&fasthttp.Client{}, fetcherOptions{})
 
-	for _, s := range []string{nonExistentURL, ":"} {
-		_, err := f.Fetch(s)
+	for _, c := range []struct {
+		url  string
+		kind ErrorKind
+	}{
+		{nonExistentURL, ErrorKindHTTPStatus},
+		{":", ErrorKindParse},
+		{noResponseURL, ErrorKindConnectionRefused},
+	} {
+		_, err := f.Fetch(c.url)
 
 		assert.NotNil(t, err)
+		assert.Equal(t, c.kind, errorKindOf(err))
+	}
+}
+
+func TestFetcherFetchWithRetries(t *testing.T) {
+	i := int32(0)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&i, 1) < 3 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer s.Close()
+
+	f := newFetcher(&fasthttp.Client{}, fetcherOptions{
+		Retries:           1,
+		RetriedErrorKinds: newErrorKindSet(ErrorKindHTTPStatus),
+	})
+	_, err := f.Fetch(s.URL)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&i))
+	assert.Equal(t, 1, f.metrics.retries)
+
+	_, err = newFetcher(&fasthttp.Client{}, fetcherOptions{
+		Retries:           2,
+		RetriedErrorKinds: newErrorKindSet(ErrorKindHTTPStatus),
+	}).Fetch(s.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&i))
+}
+
+func TestFetcherFetchWithBothAddressFamilies(t *testing.T) {
+	for _, x := range []struct {
+		v4, v6    int
+		addresses []string
+		err       string
+	}{
+		{200, 200, []string{"::1", "127.0.0.1"}, ""},
+		{503, 200, []string{"::1", "127.0.0.1"}, "unreachable over IPv4: 503"},
+		{200, 0, []string{"::1", "127.0.0.1"}, "unreachable over IPv6: "},
+		{200, 0, []string{"127.0.0.1"}, ""},
+	} {
+		p, close := listenHTTP(t, x.v4, x.v6)
+
+		c := &fasthttp.Client{Dial: newTestDialer("dual", x.addresses...).Dial}
+		f := newFetcher(c, fetcherOptions{})
+		f.familyClients = newFamilyClients(c, []fasthttp.DialFunc{
+			newTestDialer("ipv4", x.addresses...).Dial,
+			newTestDialer("ipv6", x.addresses...).Dial,
+		})
+
+		_, err := f.Fetch("http://foo.invalid:" + p)
+
+		if x.err == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.Equal(t, ErrorKindAddressFamily, errorKindOf(err))
+			assert.True(t, strings.HasPrefix(err.Error(), x.err))
+		}
+
+		close()
+	}
+}
+
+func TestNewFetcherWithBothDialMode(t *testing.T) {
+	assert.Nil(t, newFetcher(&fasthttp.Client{}, fetcherOptions{}).familyClients)
+	assert.Equal(t, 2, len(newFetcher(&fasthttp.Client{}, fetcherOptions{DialMode: "both"}).familyClients))
+}
+
+// listenHTTP serves responses of given status codes over IPv4 and IPv6 at the
+// same port. Servers of zero status codes are not started.
+func listenHTTP(t *testing.T, v4, v6 int) (string, func()) {
+	p, close := listenTCP(t, false, false)
+	close()
+
+	ss := []*http.Server{}
+
+	for _, x := range []struct {
+		address string
+		status  int
+	}{
+		{"127.0.0.1", v4},
+		{"::1", v6},
+	} {
+		if x.status == 0 {
+			continue
+		}
+
+		l, err := net.Listen("tcp", net.JoinHostPort(x.address, p))
+		assert.Nil(t, err)
+
+		n := x.status
+		s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(n)
+		})}
+		ss = append(ss, s)
+
+		go s.Serve(l)
+	}
+
+	return p, func() {
+		for _, s := range ss {
+			s.Close()
+		}
 	}
 }
 
@@ -187,3 +525,40 @@ func TestSeparateFragmentError(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestResolveLocation(t *testing.T) {
+	for _, ss := range [][3]string{
+		{"http://foo.com/bar/baz", "/qux", "http://foo.com/qux"},
+		{"http://foo.com/bar/baz", "qux", "http://foo.com/bar/qux"},
+		{"http://foo.com/bar/baz", "../qux", "http://foo.com/qux"},
+		{"https://foo.com/bar", "//baz.com/qux", "https://baz.com/qux"},
+		{"http://foo.com/bar", "https://baz.com/", "https://baz.com/"},
+		{"http://foo.com/bar", "?baz=qux", "http://foo.com/bar?baz=qux"},
+	} {
+		s, err := resolveLocation(ss[0], ss[1])
+
+		assert.Nil(t, err)
+		assert.Equal(t, ss[2], s)
+	}
+}
+
+func TestResolveLocationError(t *testing.T) {
+	_, err := resolveLocation("http://foo.com", ":")
+	assert.NotNil(t, err)
+}
+
+func TestCheckRedirectLoop(t *testing.T) {
+	assert.Nil(t, checkRedirectLoop([]redirect{
+		newRedirect("http://foo.com/a", 302, "http://foo.com/b"),
+		newRedirect("http://foo.com/b", 302, "http://foo.com/c"),
+	}))
+
+	err := checkRedirectLoop([]redirect{
+		newRedirect("http://foo.com/a", 302, "http://foo.com/b"),
+		newRedirect("http://foo.com/b", 302, "http://foo.com/c"),
+		newRedirect("http://foo.com/c", 302, "http://foo.com/b"),
+	})
+
+	assert.Equal(t, ErrorKindRedirectLoop, errorKindOf(err))
+	assert.Equal(t, "redirect loop: http://foo.com/b -> http://foo.com/c -> http://foo.com/b", err.Error())
+}
@@ -0,0 +1,28 @@
+package muffet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomically writes a file so that readers never see it partially
+// written.
+func writeFileAtomically(f string, bs []byte) error {
+	t, err := ioutil.TempFile(filepath.Dir(f), filepath.Base(f)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(t.Name())
+
+	if _, err := t.Write(bs); err != nil {
+		t.Close()
+		return err
+	} else if err := t.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(t.Name(), f)
+}
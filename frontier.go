@@ -0,0 +1,287 @@
+package muffet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+)
+
+// pageEntry is a page waiting to be checked at a depth.
+type pageEntry struct {
+	page  *page
+	depth int
+}
+
+// frontierLevel is a queue of pages at the same depth. Pages spilled into a
+// temporary file come after ones in memory.
+type frontierLevel struct {
+	entries []*page
+	file    *os.File
+	offset  int64
+	size    int64
+	spilled int
+}
+
+func (l *frontierLevel) Len() int {
+	return len(l.entries) + l.spilled
+}
+
+// spilledPage is a page in a temporary file. Only fields needed to check its
+// links are kept.
+type spilledPage struct {
+	URL     string                  `json:"url"`
+	Links   []linkResult            `json:"links"`
+	Sources map[string][]linkSource `json:"sources"`
+}
+
+// frontier is a queue of pages waiting to be checked in the order of their
+// depths. Pages over a limit are spilled into temporary files from the
+// deepest ones so that its memory usage is bounded and shallower pages stay
+// in memory. It is not safe for concurrent use.
+type frontier struct {
+	limit  int
+	size   int
+	levels map[int]*frontierLevel
+	err    error
+}
+
+func newFrontier(n int) *frontier {
+	return &frontier{n, 0, map[int]*frontierLevel{}, nil}
+}
+
+func (f *frontier) Len() int {
+	n := 0
+
+	for _, l := range f.levels {
+		n += l.Len()
+	}
+
+	return n
+}
+
+// Err returns an error on loading spilled pages. Pages which fail to be
+// loaded are dropped.
+func (f *frontier) Err() error {
+	return f.err
+}
+
+// Push adds a page at a depth. If a temporary file cannot be written, pages
+// are kept in memory instead.
+func (f *frontier) Push(p *page, d int) {
+	l, ok := f.levels[d]
+
+	if !ok {
+		l = &frontierLevel{}
+		f.levels[d] = l
+	}
+
+	if f.size >= f.limit {
+		if e, ok := f.deepestLevelInMemory(); ok && e > d {
+			f.evict(f.levels[e])
+		}
+	}
+
+	if l.spilled == 0 && f.size < f.limit || f.spill(l, p) != nil {
+		l.entries = append(l.entries, p)
+		f.size++
+	}
+}
+
+// Pop removes a page with the smallest depth.
+func (f *frontier) Pop() (pageEntry, bool) {
+	for {
+		d, ok := f.shallowestLevel()
+
+		if !ok {
+			return pageEntry{}, false
+		}
+
+		l := f.levels[d]
+
+		if len(l.entries) == 0 {
+			f.load(l)
+		}
+
+		if len(l.entries) == 0 {
+			f.removeLevel(d)
+			continue
+		}
+
+		p := l.entries[0]
+		l.entries[0] = nil
+		l.entries = l.entries[1:]
+		f.size--
+
+		if l.Len() == 0 {
+			f.removeLevel(d)
+		}
+
+		return pageEntry{p, d}, true
+	}
+}
+
+// Close removes temporary files.
+func (f *frontier) Close() error {
+	err := error(nil)
+
+	for d := range f.levels {
+		if e := f.removeLevel(d); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+func (f *frontier) shallowestLevel() (int, bool) {
+	d, ok := 0, false
+
+	for e, l := range f.levels {
+		if l.Len() != 0 && (!ok || e < d) {
+			d, ok = e, true
+		}
+	}
+
+	return d, ok
+}
+
+func (f *frontier) deepestLevelInMemory() (int, bool) {
+	d, ok := 0, false
+
+	for e, l := range f.levels {
+		if len(l.entries) != 0 && (!ok || e > d) {
+			d, ok = e, true
+		}
+	}
+
+	return d, ok
+}
+
+func (f *frontier) removeLevel(d int) error {
+	l := f.levels[d]
+	delete(f.levels, d)
+	f.size -= len(l.entries)
+
+	if l.file == nil {
+		return nil
+	}
+
+	n := l.file.Name()
+
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(n)
+}
+
+// evict spills the newest page in memory at a level.
+func (f *frontier) evict(l *frontierLevel) {
+	i := len(l.entries) - 1
+
+	if f.spill(l, l.entries[i]) == nil {
+		l.entries[i] = nil
+		l.entries = l.entries[:i]
+		f.size--
+	}
+}
+
+func (f *frontier) spill(l *frontierLevel, p *page) error {
+	if l.file == nil {
+		x, err := ioutil.TempFile("", "muffet-frontier-")
+
+		if err != nil {
+			return err
+		}
+
+		l.file = x
+	}
+
+	s := spilledPage{p.URL().String(), make([]linkResult, 0, len(p.Links())), p.Sources()}
+
+	for u, err := range p.Links() {
+		s.Links = append(s.Links, newLinkResult(u, 0, err))
+	}
+
+	bs, err := json.Marshal(s)
+
+	if err != nil {
+		return err
+	}
+
+	bs = append(bs, '\n')
+
+	if _, err := l.file.WriteAt(bs, l.size); err != nil {
+		return err
+	}
+
+	l.size += int64(len(bs))
+	l.spilled++
+
+	return nil
+}
+
+// load loads spilled pages at a level into memory up to a limit. At least
+// one page is loaded so that pages are always checked in order.
+func (f *frontier) load(l *frontierLevel) {
+	r := bufio.NewReader(io.NewSectionReader(l.file, l.offset, l.size-l.offset))
+
+	for l.spilled > 0 && (len(l.entries) == 0 || f.size < f.limit) {
+		bs, err := r.ReadBytes('\n')
+
+		if err == nil {
+			l.offset += int64(len(bs))
+			l.spilled--
+		}
+
+		p, err := unmarshalSpilledPage(bs, err)
+
+		if err != nil {
+			f.err = fmt.Errorf("failed to load %v pages waiting to be checked: %v", l.spilled, err)
+			l.spilled = 0
+			l.offset = l.size
+			break
+		}
+
+		l.entries = append(l.entries, p)
+		f.size++
+	}
+
+	if l.offset == l.size {
+		l.offset, l.size = 0, 0
+
+		if err := l.file.Truncate(0); err != nil && f.err == nil {
+			f.err = err
+		}
+	}
+}
+
+func unmarshalSpilledPage(bs []byte, err error) (*page, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	s := spilledPage{}
+
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(s.URL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ls := make(map[string]error, len(s.Links))
+
+	for _, l := range s.Links {
+		ls[l.URL()] = l.Error()
+	}
+
+	return &page{u, nil, ls, s.Sources}, nil
+}
@@ -0,0 +1,130 @@
+package muffet
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFrontierPage(t *testing.T, s string) *page {
+	p, err := newPage(s, []byte(`<a href="/foo" /><a href="gopher://bar" />`), newScraper(nil, defaultSchemes))
+	assert.Nil(t, err)
+
+	return p
+}
+
+func TestFrontierPushAndPop(t *testing.T) {
+	f := newFrontier(42)
+	defer f.Close()
+
+	for i, d := range []int{2, 0, 1, 0} {
+		f.Push(newTestFrontierPage(t, "https://foo.com/"+strconv.Itoa(i)), d)
+	}
+
+	assert.Equal(t, 4, f.Len())
+
+	for _, l := range f.levels {
+		assert.Nil(t, l.file)
+	}
+
+	for _, s := range []string{"1", "3", "2", "0"} {
+		e, ok := f.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, "https://foo.com/"+s, e.page.URL().String())
+	}
+
+	_, ok := f.Pop()
+	assert.False(t, ok)
+}
+
+func TestFrontierPushAndPopWithSpilledPages(t *testing.T) {
+	f := newFrontier(2)
+
+	for i := 9; i >= 0; i-- {
+		f.Push(newTestFrontierPage(t, "https://foo.com/"+strconv.Itoa(i)), i)
+	}
+
+	assert.Equal(t, 10, f.Len())
+	assert.Equal(t, 2, f.size)
+	assert.Equal(t, 1, len(f.levels[0].entries))
+	assert.Equal(t, 1, len(f.levels[1].entries))
+
+	for i := 0; i < 10; i++ {
+		e, ok := f.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, "https://foo.com/"+strconv.Itoa(i), e.page.URL().String())
+		assert.Equal(t, newTestFrontierPage(t, "https://foo.com/"+strconv.Itoa(i)).Links(), e.page.Links())
+		assert.Equal(t, i, e.depth)
+		assert.True(t, f.size <= 2)
+	}
+
+	assert.Equal(t, 0, f.Len())
+	assert.Equal(t, 0, len(f.levels))
+}
+
+func TestFrontierPushAndPopWithSpilledPagesAtSameDepth(t *testing.T) {
+	f := newFrontier(2)
+
+	for i := 0; i < 4; i++ {
+		f.Push(newTestFrontierPage(t, "https://foo.com/"+strconv.Itoa(i)), 0)
+	}
+
+	l := f.levels[0]
+	assert.Equal(t, 2, l.spilled)
+
+	for i := 0; i < 3; i++ {
+		e, ok := f.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, "https://foo.com/"+strconv.Itoa(i), e.page.URL().String())
+	}
+
+	assert.Equal(t, 0, l.spilled)
+	assert.Equal(t, int64(0), l.offset)
+	assert.Equal(t, int64(0), l.size)
+
+	n := l.file.Name()
+	assert.Nil(t, f.Close())
+
+	_, err := os.Stat(n)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFrontierPopWithBrokenFile(t *testing.T) {
+	f := newFrontier(1)
+	defer f.Close()
+
+	for i := 0; i < 3; i++ {
+		f.Push(newTestFrontierPage(t, "https://foo.com/"+strconv.Itoa(i)), 0)
+	}
+
+	f.Push(newTestFrontierPage(t, "https://foo.com/bar"), 1)
+	assert.Nil(t, f.levels[0].file.Truncate(1))
+
+	e, ok := f.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, "https://foo.com/0", e.page.URL().String())
+	assert.Nil(t, f.Err())
+
+	e, ok = f.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, "https://foo.com/bar", e.page.URL().String())
+	assert.NotNil(t, f.Err())
+
+	_, ok = f.Pop()
+	assert.False(t, ok)
+}
+
+func TestFrontierClose(t *testing.T) {
+	assert.Nil(t, newFrontier(1).Close())
+}
+
+func TestUnmarshalSpilledPageError(t *testing.T) {
+	_, err := unmarshalSpilledPage(nil, errors.New("foo"))
+	assert.NotNil(t, err)
+
+	_, err = unmarshalSpilledPage([]byte("{"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,291 @@
+package muffet
+
+import (
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+)
+
+// htmlReport is a self-contained HTML report of page results.
+type htmlReport struct {
+	url     string
+	results []pageResult
+	reason  string
+	orphans []orphan
+}
+
+// newHTMLReport creates a report. A non-empty reason means that a check is
+// incomplete.
+func newHTMLReport(u string, rs []pageResult, reason string) htmlReport {
+	return htmlReport{u, rs, reason, nil}
+}
+
+func (r htmlReport) WithOrphans(xs []orphan) htmlReport {
+	r.orphans = xs
+	return r
+}
+
+type htmlReportLink struct {
+	Page       string
+	URL        string
+	StatusCode int
+	Kind       ErrorKind
+	Error      string
+	Sources    []htmlReportSource
+}
+
+type htmlReportSource struct {
+	URL       string
+	Tag       string
+	Attribute string
+	Value     string
+	Line      int
+	Column    int
+	Text      string
+	HeadingID string
+}
+
+type htmlReportGroup struct {
+	URL   string
+	Links []htmlReportLink
+}
+
+type htmlReportOrphan struct {
+	URL  string
+	Kind string
+}
+
+type htmlReportKind struct {
+	Kind  ErrorKind
+	Count int
+}
+
+type htmlReportData struct {
+	URL        string
+	Incomplete string
+	PageCount  int
+	LinkCount  int
+	Warnings   int
+	Links      []htmlReportLink
+	Kinds      []htmlReportKind
+	Pages      []htmlReportGroup
+	Targets    []htmlReportGroup
+	Orphans    []htmlReportOrphan
+}
+
+func (r htmlReport) Write(w io.Writer) error {
+	return htmlReportTemplate.Execute(w, r.data())
+}
+
+func (r htmlReport) data() htmlReportData {
+	d := htmlReportData{URL: r.url, Incomplete: r.reason, PageCount: len(r.results)}
+	ks := map[ErrorKind]int{}
+
+	for _, p := range r.results {
+		d.LinkCount += len(p.Links())
+
+		for _, l := range p.Links() {
+			d.Warnings += len(l.Warnings())
+
+			if l.OK() || l.Ignored() {
+				continue
+			}
+
+			d.Links = append(d.Links, htmlReportLink{
+				p.URL(),
+				l.URL(),
+				l.StatusCode(),
+				l.ErrorKind(),
+				l.Error().Error(),
+				newHTMLReportSources(p.URL(), l.Sources()),
+			})
+			ks[l.ErrorKind()]++
+		}
+	}
+
+	sort.SliceStable(d.Links, func(i, j int) bool {
+		if d.Links[i].Page != d.Links[j].Page {
+			return d.Links[i].Page < d.Links[j].Page
+		}
+
+		return d.Links[i].URL < d.Links[j].URL
+	})
+
+	for _, k := range errorKinds {
+		if n, ok := ks[k]; ok {
+			d.Kinds = append(d.Kinds, htmlReportKind{k, n})
+		}
+	}
+
+	d.Pages = groupHTMLReportLinks(d.Links, func(l htmlReportLink) string { return l.Page })
+	d.Targets = groupHTMLReportLinks(d.Links, func(l htmlReportLink) string { return l.URL })
+
+	for _, o := range r.orphans {
+		d.Orphans = append(d.Orphans, htmlReportOrphan{o.URL(), o.Kind()})
+	}
+
+	return d
+}
+
+// newHTMLReportSources converts sources of a link in a page with links to
+// the nearest headings.
+func newHTMLReportSources(u string, ls []linkSource) []htmlReportSource {
+	ss := make([]htmlReportSource, 0, len(ls))
+
+	for _, l := range ls {
+		v := u
+
+		if l.HeadingID() != "" {
+			v += "#" + url.PathEscape(l.HeadingID())
+		}
+
+		ss = append(ss, htmlReportSource{v, l.Tag(), l.Attribute(), l.Value(), l.Line(), l.Column(), l.Text(), l.HeadingID()})
+	}
+
+	return ss
+}
+
+func groupHTMLReportLinks(ls []htmlReportLink, key func(htmlReportLink) string) []htmlReportGroup {
+	m := map[string][]htmlReportLink{}
+
+	for _, l := range ls {
+		m[key(l)] = append(m[key(l)], l)
+	}
+
+	gs := make([]htmlReportGroup, 0, len(m))
+
+	for u, ls := range m {
+		gs = append(gs, htmlReportGroup{u, ls})
+	}
+
+	sort.Slice(gs, func(i, j int) bool {
+		if len(gs[i].Links) != len(gs[j].Links) {
+			return len(gs[i].Links) > len(gs[j].Links)
+		}
+
+		return gs[i].URL < gs[j].URL
+	})
+
+	return gs
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Link check report for {{.URL}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; word-break: break-all; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3em 0.5em; text-align: left; vertical-align: top; word-break: break-all; }
+th { cursor: pointer; background: #f4f4f4; user-select: none; }
+th[data-order="asc"]::after { content: " \25b2"; }
+th[data-order="desc"]::after { content: " \25bc"; }
+details { margin: 0.3em 0; }
+summary { cursor: pointer; word-break: break-all; }
+ul { margin: 0.3em 0; }
+.summary span { display: inline-block; margin-right: 2em; }
+.incomplete { color: #b00; font-weight: bold; }
+.ok { color: #080; }
+.kind, code { font-family: monospace; }
+.source { display: block; color: #555; font-size: 0.9em; }
+.controls { margin: 1em 0; }
+.controls label { margin-right: 1em; white-space: nowrap; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>Link check report for <a href="{{.URL}}">{{.URL}}</a></h1>
+{{if .Incomplete}}<p class="incomplete">Check incomplete: {{.Incomplete}}; results below are partial.</p>{{end}}
+<p class="summary">
+<span>Pages: {{.PageCount}}</span>
+<span>Links: {{.LinkCount}}</span>
+<span>Broken links: {{len .Links}}</span>
+<span>Warnings: {{.Warnings}}</span>
+{{if .Orphans}}<span>Orphans: {{len .Orphans}}</span>{{end}}
+</p>
+{{if .Links}}
+<div class="controls">
+<input id="filter" type="search" placeholder="Filter by URL or error">
+{{range .Kinds}}<label><input class="facet" type="checkbox" value="{{.Kind}}" checked> <span class="kind">{{.Kind}}</span> ({{.Count}})</label>
+{{end}}
+</div>
+<h2>Broken links</h2>
+<table id="links">
+<thead><tr><th>Page</th><th>Link</th><th data-type="number">Status</th><th>Kind</th><th>Error</th><th>Source</th></tr></thead>
+<tbody>
+{{range .Links}}<tr class="link" data-kind="{{.Kind}}"><td><a href="{{.Page}}">{{.Page}}</a></td><td>{{.URL}}</td><td>{{if .StatusCode}}{{.StatusCode}}{{end}}</td><td class="kind">{{.Kind}}</td><td>{{.Error}}</td><td>{{template "sources" .Sources}}</td></tr>
+{{end}}
+</tbody>
+</table>
+<h2>By page</h2>
+{{range .Pages}}<details class="group"><summary><a href="{{.URL}}">{{.URL}}</a> ({{len .Links}})</summary><ul>
+{{range .Links}}<li class="link" data-kind="{{.Kind}}">{{.URL}} <span class="kind">{{.Kind}}</span> {{.Error}}{{template "sources" .Sources}}</li>
+{{end}}</ul></details>
+{{end}}
+<h2>By target</h2>
+{{range .Targets}}<details class="group"><summary>{{.URL}} ({{len .Links}})</summary><ul>
+{{range .Links}}<li class="link" data-kind="{{.Kind}}"><a href="{{.Page}}">{{.Page}}</a> <span class="kind">{{.Kind}}</span> {{.Error}}{{template "sources" .Sources}}</li>
+{{end}}</ul></details>
+{{end}}
+{{else}}
+<p class="ok">No broken links found.</p>
+{{end}}
+{{if .Orphans}}
+<h2>Orphans</h2>
+<ul>
+{{range .Orphans}}<li><span class="kind">{{.Kind}}</span> <a href="{{.URL}}">{{.URL}}</a></li>
+{{end}}</ul>
+{{end}}
+<script>
+(function () {
+  var filter = document.getElementById("filter");
+
+  if (!filter) {
+    return;
+  }
+
+  var facets = document.querySelectorAll(".facet");
+
+  function update() {
+    var q = filter.value.toLowerCase(), kinds = {};
+
+    facets.forEach(function (f) { kinds[f.value] = f.checked; });
+
+    document.querySelectorAll(".link").forEach(function (e) {
+      var shown = kinds[e.dataset.kind] && e.textContent.toLowerCase().indexOf(q) >= 0;
+      e.classList.toggle("hidden", !shown);
+    });
+
+    document.querySelectorAll(".group").forEach(function (g) {
+      g.classList.toggle("hidden", !g.querySelector(".link:not(.hidden)"));
+    });
+  }
+
+  filter.addEventListener("input", update);
+  facets.forEach(function (f) { f.addEventListener("change", update); });
+
+  document.querySelectorAll("#links th").forEach(function (h, i) {
+    h.addEventListener("click", function () {
+      var asc = h.dataset.order !== "asc", body = document.querySelector("#links tbody");
+      var rows = Array.prototype.slice.call(body.rows);
+
+      rows.sort(function (a, b) {
+        var x = a.cells[i].textContent, y = b.cells[i].textContent;
+        var c = h.dataset.type === "number" ? (Number(x) || 0) - (Number(y) || 0) : x.localeCompare(y);
+        return asc ? c : -c;
+      });
+
+      rows.forEach(function (r) { body.appendChild(r); });
+      document.querySelectorAll("#links th").forEach(function (k) { delete k.dataset.order; });
+      h.dataset.order = asc ? "asc" : "desc";
+    });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "sources"}}{{range .}}<span class="source"><a href="{{.URL}}">line {{.Line}}, column {{.Column}}</a> <code>&lt;{{.Tag}} {{.Attribute}}="{{.Value}}"&gt;</code>{{if .Text}} &ldquo;{{.Text}}&rdquo;{{end}}{{if .HeadingID}} under #{{.HeadingID}}{{end}}</span>{{end}}{{end}}`))
@@ -0,0 +1,101 @@
+package muffet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLReportWrite(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	assert.Nil(t, newHTMLReport(rootURL, []pageResult{
+		newPageResult(rootURL, []linkResult{
+			newLinkResult(existentURL, 200, nil),
+			newLinkResult(nonExistentURL, 404, fetchError{ErrorKindHTTPStatus, 404, errors.New("404")}),
+			newLinkResult(`http://foo.com/<script>`, 0, errors.New("oops")),
+		}),
+	}, "").Write(b))
+
+	s := b.String()
+
+	assert.True(t, strings.HasPrefix(s, "<!DOCTYPE html>"))
+	assert.Contains(t, s, "Broken links: 2")
+	assert.Contains(t, s, nonExistentURL)
+	assert.Contains(t, s, `value="http-status"`)
+	assert.Contains(t, s, `value="other"`)
+	assert.Contains(t, s, "&lt;script&gt;")
+	assert.NotContains(t, s, "<script>\"")
+	assert.NotContains(t, s, "Check incomplete")
+	assert.NotContains(t, s, "<link")
+	assert.NotContains(t, s, " src=")
+}
+
+func TestHTMLReportWriteWithoutBrokenLinks(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	assert.Nil(t, newHTMLReport(rootURL, []pageResult{
+		newPageResult(rootURL, []linkResult{newLinkResult(existentURL, 200, nil)}),
+	}, "interrupted").Write(b))
+
+	assert.Contains(t, b.String(), "No broken links found.")
+	assert.Contains(t, b.String(), "Check incomplete: interrupted")
+}
+
+func TestHTMLReportWriteWithSources(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	assert.Nil(t, newHTMLReport(rootURL, []pageResult{
+		newPageResult(rootURL, []linkResult{
+			newLinkResult(nonExistentURL, 404, fetchError{ErrorKindHTTPStatus, 404, errors.New("404")}).
+				WithSources([]linkSource{newLinkSource("a", "href", "/bar", 12, 5, "Bar", "foo")}),
+		}),
+	}, "").Write(b))
+
+	assert.Contains(t, b.String(), "line 12, column 5")
+	assert.Contains(t, b.String(), `&lt;a href="/bar"&gt;`)
+	assert.Contains(t, b.String(), `href="`+rootURL+`#foo"`)
+	assert.Contains(t, b.String(), "Bar")
+}
+
+func TestHTMLReportWriteWithOrphans(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	assert.Nil(t, newHTMLReport(rootURL, nil, "").WithOrphans([]orphan{newOrphan(rootURL + "/foo.png")}).Write(b))
+
+	assert.Contains(t, b.String(), "Orphans: 1")
+	assert.Contains(t, b.String(), rootURL+"/foo.png")
+}
+
+func TestHTMLReportData(t *testing.T) {
+	e := fetchError{ErrorKindHTTPStatus, 404, errors.New("404")}
+
+	d := newHTMLReport(rootURL, []pageResult{
+		newPageResult(existentURL, []linkResult{
+			newLinkResult(nonExistentURL, 404, e),
+		}),
+		newPageResult(rootURL, []linkResult{
+			newLinkResult(nonExistentURL, 404, e),
+			newLinkResult(erroneousURL, 404, e),
+			newLinkResult(fragmentURL, 404, e).Ignore(),
+			newLinkResult(existentURL, 200, nil).Warn("foo"),
+		}),
+	}, "").data()
+
+	assert.Equal(t, 2, d.PageCount)
+	assert.Equal(t, 5, d.LinkCount)
+	assert.Equal(t, 1, d.Warnings)
+	assert.Equal(t, []htmlReportKind{{ErrorKindHTTPStatus, 3}}, d.Kinds)
+	assert.Equal(t, rootURL, d.Links[0].Page)
+	assert.Equal(t, nonExistentURL, d.Links[0].URL)
+	assert.Equal(t, existentURL, d.Links[2].Page)
+
+	assert.Equal(t, rootURL, d.Pages[0].URL)
+	assert.Len(t, d.Pages[0].Links, 2)
+	assert.Equal(t, nonExistentURL, d.Targets[0].URL)
+	assert.Len(t, d.Targets[0].Links, 2)
+	assert.Len(t, d.Targets, 2)
+}
@@ -0,0 +1,120 @@
+package muffet
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var headingAtoms = map[atom.Atom]struct{}{
+	atom.H1: {},
+	atom.H2: {},
+	atom.H3: {},
+	atom.H4: {},
+	atom.H5: {},
+	atom.H6: {},
+}
+
+// htmlScan is a summary of an HTML document needed to check links in it.
+type htmlScan struct {
+	ids     idSet
+	base    string
+	sources []linkSource
+	err     error
+}
+
+// scanHTML scans an HTML document with a tokenizer in a single pass. Unlike a
+// parser, it does not build a whole tree of the document in memory. An error
+// on reading the document is kept in its summary.
+func scanHTML(r io.Reader) htmlScan {
+	z := html.NewTokenizer(r)
+	d := htmlScan{newIDSet(), "", nil, nil}
+	l, c, h, b := 1, 1, "", false
+	a, tb := -1, &strings.Builder{}
+
+	for {
+		t := z.Next()
+
+		if t == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				d.err = err
+			}
+
+			return d
+		}
+
+		r := z.Raw()
+		k := z.Token()
+
+		switch t {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// 6.7.9. Navigating to a fragment
+			// http://w3c.github.io/html/browsers.html#navigating-to-a-fragment-identifier
+			if s, ok := tokenAttr(k, "id"); ok && s != "" {
+				d.ids.Add(s)
+
+				if _, ok := headingAtoms[k.DataAtom]; ok {
+					h = s
+				}
+			}
+
+			if k.DataAtom == atom.A {
+				if s, ok := tokenAttr(k, "name"); ok && s != "" {
+					d.ids.Add(s)
+				}
+			}
+
+			// Only the first base element with a href attribute is used.
+			if k.DataAtom == atom.Base && !b {
+				d.base, b = tokenAttr(k, "href")
+			}
+
+			s, _ := tokenAttr(k, "alt")
+
+			if k.DataAtom == atom.A && t == html.StartTagToken {
+				a = len(d.sources)
+				tb.Reset()
+			}
+
+			for _, x := range atomToAttributes[k.DataAtom] {
+				if v, ok := tokenAttr(k, x); ok {
+					d.sources = append(d.sources, newLinkSource(k.Data, x, v, l, c, s, h))
+				}
+			}
+		case html.TextToken:
+			if a >= 0 {
+				tb.WriteString(k.Data)
+			}
+		case html.EndTagToken:
+			if k.DataAtom == atom.A && a >= 0 {
+				for i := a; i < len(d.sources); i++ {
+					if d.sources[i].tag == "a" {
+						d.sources[i].text = strings.Join(strings.Fields(tb.String()), " ")
+					}
+				}
+
+				a = -1
+			}
+		}
+
+		for _, x := range string(r) {
+			if x == '\n' {
+				l, c = l+1, 1
+			} else {
+				c++
+			}
+		}
+	}
+}
+
+func tokenAttr(t html.Token, k string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Namespace == "" && a.Key == k {
+			return a.Val, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,55 @@
+package muffet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanHTMLSources(t *testing.T) {
+	ls := scanHTML(strings.NewReader(`<html>
+<body>
+  <h1 id="top">Top</h1>
+  <a href="/foo">Foo
+    <b>bar</b></a>
+  <h2 id="images">Images</h2>
+  <p>日本 <img src="foo.png" alt="A foo" /></p>
+  <source src="foo.mp4" srcset="foo.jpg">
+  <script>var s = '<a href="/bar">';</script>
+  <a href="/baz" />
+</body>
+</html>`)).sources
+
+	assert.Equal(t, []linkSource{
+		newLinkSource("a", "href", "/foo", 4, 3, "Foo bar", "top"),
+		newLinkSource("img", "src", "foo.png", 7, 9, "A foo", "images"),
+		newLinkSource("source", "src", "foo.mp4", 8, 3, "", "images"),
+		newLinkSource("source", "srcset", "foo.jpg", 8, 3, "", "images"),
+		newLinkSource("a", "href", "/baz", 10, 3, "", "images"),
+	}, ls)
+}
+
+func TestScanHTMLWithEmptySource(t *testing.T) {
+	assert.Nil(t, scanHTML(strings.NewReader("")).sources)
+}
+
+func TestScanHTMLIDs(t *testing.T) {
+	d := scanHTML(strings.NewReader(`<p id="foo">Hello!</p><a name="bar"></a><p name="baz"></p>`))
+
+	assert.Equal(t, 2, len(d.ids))
+	assert.True(t, d.ids.Contains("foo"))
+	assert.True(t, d.ids.Contains("bar"))
+}
+
+func TestScanHTMLBase(t *testing.T) {
+	for _, c := range []struct {
+		html, base string
+	}{
+		{``, ""},
+		{`<base href="/foo/" /><base href="/bar/" />`, "/foo/"},
+		{`<base target="_blank" /><base href="/bar/" />`, "/bar/"},
+	} {
+		assert.Equal(t, c.base, scanHTML(strings.NewReader(c.html)).base)
+	}
+}
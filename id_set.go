@@ -0,0 +1,36 @@
+package muffet
+
+import "hash/fnv"
+
+// idSet is a set of element IDs in a page. It stores hashes of IDs to save
+// memory as it is kept in a cache during a whole check.
+type idSet map[uint64]struct{}
+
+func newIDSet(ss ...string) idSet {
+	s := make(idSet, len(ss))
+
+	for _, x := range ss {
+		s.Add(x)
+	}
+
+	return s
+}
+
+func (s idSet) Add(x string) {
+	s[hashID(x)] = struct{}{}
+}
+
+func (s idSet) Contains(x string) bool {
+	_, ok := s[hashID(x)]
+	return ok
+}
+
+func hashID(s string) uint64 {
+	h := fnv.New64a()
+
+	if _, err := h.Write([]byte(s)); err != nil {
+		panic(err)
+	}
+
+	return h.Sum64()
+}
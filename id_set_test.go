@@ -0,0 +1,19 @@
+package muffet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDSet(t *testing.T) {
+	s := newIDSet("foo")
+
+	assert.True(t, s.Contains("foo"))
+	assert.False(t, s.Contains("bar"))
+
+	s.Add("bar")
+
+	assert.True(t, s.Contains("bar"))
+	assert.Equal(t, 2, len(s))
+}
@@ -0,0 +1,202 @@
+package muffet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type jobStatus string
+
+const (
+	jobStatusRunning   jobStatus = "running"
+	jobStatusSucceeded jobStatus = "succeeded"
+	jobStatusFailed    jobStatus = "failed"
+	jobStatusCanceled  jobStatus = "canceled"
+)
+
+// job is a check of a website run in server mode.
+type job struct {
+	id               string
+	url              string
+	warnedErrorKinds errorKindSet
+	cancel           context.CancelFunc
+	mutex            *sync.Mutex
+	status           jobStatus
+	err              error
+	results          []pageResult
+	updated          chan struct{}
+}
+
+func newJob(id, u string, wks errorKindSet, cancel context.CancelFunc) *job {
+	return &job{id, u, wks, cancel, &sync.Mutex{}, jobStatusRunning, nil, nil, make(chan struct{})}
+}
+
+// Run checks pages with a checker created by a given function.
+func (j *job) Run(ctx context.Context, newChecker func() (checker, error)) {
+	c, err := newChecker()
+
+	if err != nil {
+		j.finish(ctx, err)
+		return
+	}
+
+	go c.Check(ctx)
+
+	for r := range c.Results() {
+		j.add(r)
+	}
+
+	j.finish(ctx, c.Err())
+}
+
+func (j *job) Cancel() {
+	j.cancel()
+}
+
+func (j *job) ID() string {
+	return j.id
+}
+
+func (j *job) Status() jobStatus {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.status
+}
+
+// Results returns page results from a given index, whether a job is finished
+// and a channel closed on the next update of the job.
+func (j *job) Results(i int) ([]pageResult, bool, <-chan struct{}) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if i > len(j.results) {
+		i = len(j.results)
+	}
+
+	return j.results[i:], j.status != jobStatusRunning, j.updated
+}
+
+func (j *job) MarshalJSON() ([]byte, error) {
+	return j.marshalJSON(false)
+}
+
+// MarshalReportJSON marshals a job with all of its page results.
+func (j *job) MarshalReportJSON() ([]byte, error) {
+	return j.marshalJSON(true)
+}
+
+func (j *job) marshalJSON(report bool) ([]byte, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	ok := j.err == nil
+
+	for _, r := range j.results {
+		if isFailedPageResult(r, j.warnedErrorKinds) {
+			ok = false
+		}
+	}
+
+	e := ""
+
+	if j.err != nil {
+		e = j.err.Error()
+	}
+
+	rs, ts := []pageResult(nil), []targetResult(nil)
+
+	if report {
+		rs = append(make([]pageResult, 0, len(j.results)), j.results...)
+		ts = newTargetResults(rs)
+	}
+
+	return json.Marshal(struct {
+		ID      string         `json:"id"`
+		URL     string         `json:"url"`
+		Status  jobStatus      `json:"status"`
+		Error   string         `json:"error,omitempty"`
+		OK      bool           `json:"ok"`
+		Count   int            `json:"pageCount"`
+		Pages   []pageResult   `json:"pages,omitempty"`
+		Targets []targetResult `json:"targets,omitempty"`
+	}{j.id, j.url, j.status, e, ok, len(j.results), rs, ts})
+}
+
+func (j *job) add(r pageResult) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.results = append(j.results, r)
+	j.notify()
+}
+
+func (j *job) finish(ctx context.Context, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		j.status = jobStatusCanceled
+		j.err = errors.New("max duration exceeded")
+	case ctx.Err() != nil:
+		j.status = jobStatusCanceled
+	case err != nil:
+		j.status = jobStatusFailed
+		j.err = err
+	default:
+		j.status = jobStatusSucceeded
+	}
+
+	j.notify()
+}
+
+// notify wakes up watchers of a job. It must be called with a lock held.
+func (j *job) notify() {
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
+
+// jobOptionsToArguments converts options of a job keyed by long option names
+// into command line arguments.
+func jobOptionsToArguments(u string, os map[string]interface{}) ([]string, error) {
+	ks := make([]string, 0, len(os))
+
+	for k := range os {
+		ks = append(ks, k)
+	}
+
+	sort.Strings(ks)
+
+	ss := []string(nil)
+
+	for _, k := range ks {
+		xs, ok := os[k].([]interface{})
+
+		if !ok {
+			xs = []interface{}{os[k]}
+		}
+
+		for _, x := range xs {
+			switch x := x.(type) {
+			case bool:
+				if x {
+					ss = append(ss, "--"+k)
+				}
+			case string:
+				ss = append(ss, "--"+k+"="+x)
+			case float64:
+				ss = append(ss, "--"+k+"="+strconv.FormatFloat(x, 'f', -1, 64))
+			default:
+				return nil, fmt.Errorf("invalid value for option %v", k)
+			}
+		}
+	}
+
+	return append(ss, u), nil
+}
@@ -0,0 +1,163 @@
+package muffet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRun(t *testing.T) {
+	j := newJob("1", rootURL, nil, func() {})
+
+	j.Run(context.Background(), func() (checker, error) {
+		return newChecker(rootURL, checkerOptions{})
+	})
+
+	rs, done, _ := j.Results(0)
+
+	assert.Equal(t, jobStatusSucceeded, j.Status())
+	assert.True(t, done)
+	assert.NotZero(t, len(rs))
+}
+
+func TestJobRunError(t *testing.T) {
+	j := newJob("1", rootURL, nil, func() {})
+
+	j.Run(context.Background(), func() (checker, error) {
+		return checker{}, errors.New("foo")
+	})
+
+	bs, err := j.MarshalJSON()
+
+	assert.Nil(t, err)
+	assert.Equal(t, jobStatusFailed, j.Status())
+	assert.Contains(t, string(bs), `"error":"foo"`)
+}
+
+func TestJobRunWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := newJob("1", rootURL, nil, cancel)
+	j.Cancel()
+
+	j.Run(ctx, func() (checker, error) {
+		return newChecker(rootURL, checkerOptions{})
+	})
+
+	assert.Equal(t, jobStatusCanceled, j.Status())
+}
+
+func TestJobRunWithDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	j := newJob("1", rootURL, nil, cancel)
+
+	j.Run(ctx, func() (checker, error) {
+		return newChecker(rootURL, checkerOptions{})
+	})
+
+	bs, err := j.MarshalJSON()
+
+	assert.Nil(t, err)
+	assert.Equal(t, jobStatusCanceled, j.Status())
+	assert.Contains(t, string(bs), `"error":"max duration exceeded"`)
+}
+
+func TestJobResults(t *testing.T) {
+	j := newJob("1", rootURL, nil, func() {})
+	rs, done, c := j.Results(0)
+
+	assert.Zero(t, len(rs))
+	assert.False(t, done)
+
+	j.add(newPageResult(rootURL, nil))
+	<-c
+
+	rs, _, _ = j.Results(0)
+	assert.Equal(t, 1, len(rs))
+
+	rs, _, _ = j.Results(2)
+	assert.Zero(t, len(rs))
+}
+
+func TestJobMarshalJSON(t *testing.T) {
+	j := newJob("1", erroneousURL, nil, func() {})
+	j.add(newPageResult(erroneousURL, []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}))
+	j.finish(context.Background(), nil)
+
+	for _, f := range []func() ([]byte, error){j.MarshalJSON, j.MarshalReportJSON} {
+		bs, err := f()
+		assert.Nil(t, err)
+
+		r := struct {
+			Status    string
+			OK        bool
+			PageCount int
+			Pages     []struct{ URL string }
+		}{}
+
+		assert.Nil(t, json.Unmarshal(bs, &r))
+		assert.Equal(t, "succeeded", r.Status)
+		assert.False(t, r.OK)
+		assert.Equal(t, 1, r.PageCount)
+	}
+}
+
+func TestJobMarshalReportJSONWithTargets(t *testing.T) {
+	j := newJob("1", erroneousURL, nil, func() {})
+	j.add(newPageResult(erroneousURL, []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}))
+	j.add(newPageResult(existentURL, []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}))
+	j.finish(context.Background(), nil)
+
+	bs, err := j.MarshalJSON()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(bs), "targets")
+
+	bs, err = j.MarshalReportJSON()
+	assert.Nil(t, err)
+
+	r := struct {
+		Targets []struct {
+			URL       string
+			PageCount int
+		}
+	}{}
+
+	assert.Nil(t, json.Unmarshal(bs, &r))
+	assert.Equal(t, 1, len(r.Targets))
+	assert.Equal(t, "foo", r.Targets[0].URL)
+	assert.Equal(t, 2, r.Targets[0].PageCount)
+}
+
+func TestJobMarshalJSONWithWarnedErrorKinds(t *testing.T) {
+	j := newJob("1", erroneousURL, newErrorKindSet(ErrorKindHTTPStatus), func() {})
+	j.add(newPageResult(erroneousURL, []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}))
+
+	bs, err := j.MarshalJSON()
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(bs), `"ok":true`)
+}
+
+func TestJobOptionsToArguments(t *testing.T) {
+	ss, err := jobOptionsToArguments("https://foo.com", map[string]interface{}{
+		"concurrency":       float64(1),
+		"exclude":           []interface{}{"foo", "bar"},
+		"follow-robots-txt": true,
+		"verbose":           false,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		[]string{"--concurrency=1", "--exclude=foo", "--exclude=bar", "--follow-robots-txt", "https://foo.com"},
+		ss)
+}
+
+func TestJobOptionsToArgumentsError(t *testing.T) {
+	_, err := jobOptionsToArguments("https://foo.com", map[string]interface{}{"foo": nil})
+	assert.NotNil(t, err)
+}
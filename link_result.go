@@ -0,0 +1,144 @@
+package muffet
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/fatih/color"
+)
+
+type linkResult struct {
+	url        string
+	statusCode int
+	err        error
+	ignored    bool
+	redirects  []redirect
+	warnings   []string
+	sources    []linkSource
+}
+
+func newLinkResult(u string, s int, err error) linkResult {
+	if e, ok := err.(fetchError); ok && s == 0 {
+		s = e.StatusCode()
+	}
+
+	return linkResult{u, s, err, false, nil, nil, nil}
+}
+
+func (r linkResult) URL() string {
+	return r.url
+}
+
+func (r linkResult) StatusCode() int {
+	return r.statusCode
+}
+
+func (r linkResult) Error() error {
+	return r.err
+}
+
+func (r linkResult) OK() bool {
+	return r.err == nil
+}
+
+// Ignore marks an erroneous link as not affecting its page's result.
+func (r linkResult) Ignore() linkResult {
+	r.ignored = true
+	return r
+}
+
+func (r linkResult) Ignored() bool {
+	return r.ignored
+}
+
+func (r linkResult) WithRedirects(rs []redirect) linkResult {
+	r.redirects = rs
+	return r
+}
+
+func (r linkResult) Redirects() []redirect {
+	return r.redirects
+}
+
+// Warn attaches warnings which do not make a link erroneous.
+func (r linkResult) Warn(ws ...string) linkResult {
+	r.warnings = append(append([]string(nil), r.warnings...), ws...)
+	return r
+}
+
+func (r linkResult) Warnings() []string {
+	return r.warnings
+}
+
+func (r linkResult) WithSources(ss []linkSource) linkResult {
+	r.sources = ss
+	return r
+}
+
+// Sources returns locations of a link in a source of its page.
+func (r linkResult) Sources() []linkSource {
+	return r.sources
+}
+
+func (r linkResult) ErrorKind() ErrorKind {
+	return errorKindOf(r.err)
+}
+
+func (r linkResult) String() string {
+	if r.err == nil {
+		return color.GreenString("%v", r.statusCode) + "\t" + r.url
+	}
+
+	return color.RedString("%v [%v]", r.err, r.ErrorKind()) + "\t" + r.url
+}
+
+func (r linkResult) WarningStrings() []string {
+	ss := make([]string, 0, len(r.warnings))
+
+	for _, w := range r.warnings {
+		ss = append(ss, color.YellowString(w)+"\t"+r.url)
+	}
+
+	return ss
+}
+
+type linkResultJSON struct {
+	URL        string       `json:"url"`
+	StatusCode int          `json:"status,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	ErrorKind  ErrorKind    `json:"kind,omitempty"`
+	Ignored    bool         `json:"ignored,omitempty"`
+	Redirects  []redirect   `json:"redirects,omitempty"`
+	Warnings   []string     `json:"warnings,omitempty"`
+	Sources    []linkSource `json:"sources,omitempty"`
+}
+
+func (r linkResult) MarshalJSON() ([]byte, error) {
+	e := ""
+
+	if r.err != nil {
+		e = r.err.Error()
+	}
+
+	return json.Marshal(linkResultJSON{r.url, r.statusCode, e, r.ErrorKind(), r.ignored, r.redirects, r.warnings, r.sources})
+}
+
+// UnmarshalJSON restores a link result. Its error keeps only a message and a
+// kind of the original one.
+func (r *linkResult) UnmarshalJSON(bs []byte) error {
+	j := linkResultJSON{}
+
+	if err := json.Unmarshal(bs, &j); err != nil {
+		return err
+	}
+
+	err := error(nil)
+
+	if j.Error != "" {
+		err = fetchError{j.ErrorKind, j.StatusCode, errors.New(j.Error)}
+	}
+
+	*r = linkResult{j.URL, j.StatusCode, err, j.Ignored, j.Redirects, j.Warnings, j.Sources}
+
+	return nil
+}
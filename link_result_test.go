@@ -0,0 +1,111 @@
+package muffet
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLinkResult(t *testing.T) {
+	assert.Equal(t, 200, newLinkResult("https://foo.com", 200, nil).StatusCode())
+	assert.Equal(t, 404, newLinkResult("https://foo.com", 0, newHTTPStatusError(404)).StatusCode())
+}
+
+func TestLinkResultOK(t *testing.T) {
+	assert.True(t, newLinkResult("https://foo.com", 200, nil).OK())
+	assert.False(t, newLinkResult("https://foo.com", 0, errors.New("foo")).OK())
+}
+
+func TestLinkResultIgnore(t *testing.T) {
+	r := newLinkResult("https://foo.com", 0, errors.New("foo"))
+
+	assert.False(t, r.Ignored())
+	assert.True(t, r.Ignore().Ignored())
+}
+
+func TestLinkResultErrorKind(t *testing.T) {
+	assert.Equal(t, ErrorKind(""), newLinkResult("https://foo.com", 200, nil).ErrorKind())
+	assert.Equal(t, ErrorKindHTTPStatus, newLinkResult("https://foo.com", 0, newHTTPStatusError(404)).ErrorKind())
+}
+
+func TestLinkResultString(t *testing.T) {
+	assert.True(t, strings.Contains(newLinkResult("https://foo.com", 200, nil).String(), "200"))
+
+	s := newLinkResult("https://foo.com", 0, newHTTPStatusError(404)).String()
+
+	assert.True(t, strings.Contains(s, "404"))
+	assert.True(t, strings.Contains(s, "http-status"))
+}
+
+func TestLinkResultMarshalJSON(t *testing.T) {
+	for _, c := range []struct {
+		result linkResult
+		answer string
+	}{
+		{
+			newLinkResult("https://foo.com", 200, nil),
+			`{"url":"https://foo.com","status":200}`,
+		},
+		{
+			newLinkResult("https://foo.com", 0, newFetchError(ErrorKindTimeout, errors.New("timeout"))).Ignore(),
+			`{"url":"https://foo.com","error":"timeout","kind":"timeout","ignored":true}`,
+		},
+	} {
+		bs, err := json.Marshal(c.result)
+
+		assert.Nil(t, err)
+		assert.Equal(t, c.answer, string(bs))
+	}
+}
+
+func TestLinkResultWithRedirects(t *testing.T) {
+	rs := []redirect{newRedirect("http://foo.com", 301, "https://foo.com")}
+
+	assert.Equal(t, rs, newLinkResult("http://foo.com", 200, nil).WithRedirects(rs).Redirects())
+}
+
+func TestLinkResultWarn(t *testing.T) {
+	r := newLinkResult("https://foo.com", 200, nil)
+
+	assert.Equal(t, []string(nil), r.Warnings())
+	assert.Equal(t, []string{"foo", "bar"}, r.Warn("foo").Warn("bar").Warnings())
+	assert.Equal(t, []string(nil), r.Warnings())
+	assert.Equal(t, 2, len(r.Warn("foo", "bar").WarningStrings()))
+}
+
+func TestLinkResultUnmarshalJSON(t *testing.T) {
+	for _, r := range []linkResult{
+		newLinkResult("https://foo.com", 200, nil),
+		newLinkResult("https://foo.com", 0, newHTTPStatusError(404)).Ignore(),
+		newLinkResult("https://foo.com", 200, nil).
+			WithRedirects([]redirect{newRedirect("https://bar.com", 301, "https://foo.com")}).
+			Warn("foo"),
+		newLinkResult("https://foo.com", 0, newHTTPStatusError(404)).
+			WithSources([]linkSource{newLinkSource("a", "href", "/foo", 3, 5, "Foo", "bar")}),
+	} {
+		bs, err := json.Marshal(r)
+		assert.Nil(t, err)
+
+		s := linkResult{}
+		assert.Nil(t, json.Unmarshal(bs, &s))
+
+		assert.Equal(t, r.URL(), s.URL())
+		assert.Equal(t, r.StatusCode(), s.StatusCode())
+		assert.Equal(t, r.ErrorKind(), s.ErrorKind())
+		assert.Equal(t, r.Ignored(), s.Ignored())
+		assert.Equal(t, r.Redirects(), s.Redirects())
+		assert.Equal(t, r.Warnings(), s.Warnings())
+		assert.Equal(t, r.Sources(), s.Sources())
+
+		if r.Error() != nil {
+			assert.Equal(t, r.Error().Error(), s.Error().Error())
+		}
+	}
+}
+
+func TestLinkResultUnmarshalJSONError(t *testing.T) {
+	assert.NotNil(t, json.Unmarshal([]byte(`{"url":0}`), &linkResult{}))
+}
@@ -0,0 +1,77 @@
+package muffet
+
+import "encoding/json"
+
+// linkSource is a location of a link in an HTML source.
+type linkSource struct {
+	tag       string
+	attribute string
+	value     string
+	line      int
+	column    int
+	text      string
+	headingID string
+}
+
+func newLinkSource(t, a, v string, l, c int, s, h string) linkSource {
+	return linkSource{t, a, v, l, c, s, h}
+}
+
+func (s linkSource) Tag() string {
+	return s.tag
+}
+
+func (s linkSource) Attribute() string {
+	return s.attribute
+}
+
+// Value returns a raw value of an attribute.
+func (s linkSource) Value() string {
+	return s.value
+}
+
+// Line returns a 1-based line number of a tag.
+func (s linkSource) Line() int {
+	return s.line
+}
+
+// Column returns a 1-based column number of a tag in runes.
+func (s linkSource) Column() int {
+	return s.column
+}
+
+// Text returns text in an anchor or an alternative text of an image.
+func (s linkSource) Text() string {
+	return s.text
+}
+
+// HeadingID returns an ID of the nearest heading before a tag.
+func (s linkSource) HeadingID() string {
+	return s.headingID
+}
+
+type linkSourceJSON struct {
+	Tag       string `json:"tag"`
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	Text      string `json:"text,omitempty"`
+	HeadingID string `json:"headingId,omitempty"`
+}
+
+func (s linkSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(linkSourceJSON{s.tag, s.attribute, s.value, s.line, s.column, s.text, s.headingID})
+}
+
+func (s *linkSource) UnmarshalJSON(bs []byte) error {
+	j := linkSourceJSON{}
+
+	if err := json.Unmarshal(bs, &j); err != nil {
+		return err
+	}
+
+	*s = newLinkSource(j.Tag, j.Attribute, j.Value, j.Line, j.Column, j.Text, j.HeadingID)
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package muffet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkSourceUnmarshalJSON(t *testing.T) {
+	s := newLinkSource("a", "href", "/foo", 4, 3, "Foo", "top")
+
+	bs, err := json.Marshal(s)
+	assert.Nil(t, err)
+
+	r := linkSource{}
+	assert.Nil(t, json.Unmarshal(bs, &r))
+	assert.Equal(t, s, r)
+}
+
+func TestLinkSourceUnmarshalJSONError(t *testing.T) {
+	assert.NotNil(t, json.Unmarshal([]byte(`{"line":"foo"}`), &linkSource{}))
+}
@@ -1,22 +1,29 @@
 package muffet
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"flag"
+	"encoding/json"
 	"fmt"
 	"github.com/valyala/fasthttp"
 	"io"
 	"io/ioutil"
-	"log"
 	"net"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 // todo it works on my machine with staging docs even without custom cert file?
 // https://forfuncsake.github.io/post/2017/08/trust-extra-ca-cert-in-go-app/
-func createTlsConfig(localCertFile string, insecure bool) *tls.Config {
+func createTlsConfig(localCertFile string, insecure bool) (*tls.Config, error) {
 	// Get the SystemCertPool, continue with an empty pool on error
 	rootCAs, _ := x509.SystemCertPool()
 	if rootCAs == nil {
@@ -27,12 +34,12 @@ func createTlsConfig(localCertFile string, insecure bool) *tls.Config {
 		// Read in the cert file
 		certs, err := ioutil.ReadFile(localCertFile)
 		if err != nil {
-			log.Fatalf("Failed to append %q to RootCAs: %v", localCertFile, err)
+			return nil, fmt.Errorf("failed to append %q to RootCAs: %v", localCertFile, err)
 		}
 
 		// Append our cert to the system pool
 		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
-			log.Println("No certs appended, using system certs only")
+			return nil, fmt.Errorf("no certificates found in %q", localCertFile)
 		}
 	}
 
@@ -42,38 +49,42 @@ func createTlsConfig(localCertFile string, insecure bool) *tls.Config {
 		RootCAs:            rootCAs,
 	}
 
-	return config
+	return config, nil
 }
 
-func serveDirectory(path string) []string {
+// serveDirectory serves a directory over HTTP in background and returns URLs
+// of index pages of documents in it.
+func serveDirectory(path string) ([]string, error) {
+	d, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	ns, err := d.Readdirnames(0)
+	if err != nil {
+		return nil, err
+	}
+
 	// Setup FS handler
 	fs := &fasthttp.FS{
-		Root: path,
-		//IndexNames:         []string{"index.html"},
+		Root:               path,
 		GenerateIndexPages: true,
-		//Compress:           *compress,
-		//AcceptByteRange:    *byteRange,
 	}
 	fsHandler := fs.NewRequestHandler()
 
 	// Start HTTP server.
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
-	mustNot(err)
+	if err != nil {
+		return nil, err
+	}
 	randomPort := listener.Addr().(*net.TCPAddr).Port
 
-	log.Printf("Starting HTTP server on %q", listener.Addr())
-	go func() {
-		if err := fasthttp.Serve(listener, fsHandler); err != nil {
-			log.Fatalf("error in ListenAndServe: %s", err)
-		}
-	}()
+	// The server runs until the process exits.
+	go fasthttp.Serve(listener, fsHandler)
 
 	urls := make([]string, 0)
 
-	d, err := os.Open(path)
-	mustNot(err)
-	ns, err := d.Readdirnames(0)
-	mustNot(err)
 	for _, dir := range ns {
 		if dir == "images" {
 			continue
@@ -88,128 +99,487 @@ func serveDirectory(path string) []string {
 			continue
 		}
 		url := fmt.Sprintf("http://127.0.0.1:%d/%s/index.html", randomPort, dir)
-		//fmt.Println(url)
 		urls = append(urls, url)
 	}
 
-	return urls
+	return urls, nil
 }
 
-func CheckListOfLinks(links []string) {
-	// doc-stage_usersys_redhat_com.crt
-	serve := flag.String("serve", "", "Directory to serve over http")
-	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
-	certFile := flag.String("cert-file", "", "Path to certificate file")
-
-	flag.Parse()
+// DocCheckOptions are options for checking documentation pages.
+type DocCheckOptions struct {
+	// ServedDirectory is a directory to serve over HTTP and check.
+	ServedDirectory string
+	// CertFile is a path to a certificate file trusted in addition to
+	// system ones.
+	CertFile    string
+	InsecureSSL bool
+	// DialMode is a mode of dialing hosts, which is "ipv4", "ipv6", "dual"
+	// or "both".
+	DialMode string
+}
 
-	tlsConfig := createTlsConfig(*certFile, *insecure)
-	f := newFetcher(&fasthttp.Client{TLSConfig: tlsConfig}, fetcherOptions{})
+func newDocCheckFetcher(c *tls.Config, o DocCheckOptions) fetcher {
+	fo := fetcherOptions{DialMode: o.DialMode}
+	fo.Initialize()
 
-	failures := make(Failures)
+	return newFetcher(&fasthttp.Client{Dial: newDialFunc(fo), TLSConfig: c}, fo)
+}
 
-	if *serve != "" {
-		links = serveDirectory(*serve)
+// CheckListOfLinks checks documentation pages at given URLs and writes
+// results to a writer.
+func CheckListOfLinks(w io.Writer, links []string, o DocCheckOptions) error {
+	tlsConfig, err := createTlsConfig(o.CertFile, o.InsecureSSL)
+	if err != nil {
+		return err
 	}
+	f := newDocCheckFetcher(tlsConfig, o)
 
-	if links == nil {
-		links = flag.Args()
+	failures := make(Failures)
+
+	if o.ServedDirectory != "" {
+		links, err = serveDirectory(o.ServedDirectory)
+		if err != nil {
+			return err
+		}
 	}
 
-	// has position args
 	for _, arg := range links {
-		checkDocPage(arg, f, failures)
+		checkDocPage(w, arg, f, failures)
 	}
-}
-
-func CheckReleased() {
-	// doc-stage_usersys_redhat_com.crt
-	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
-	certFile := flag.String("cert-file", "", "Path to certificate file")
 
-	flag.Parse()
+	return nil
+}
 
-	tlsConfig := createTlsConfig(*certFile, *insecure)
-	f := newFetcher(&fasthttp.Client{TLSConfig: tlsConfig}, fetcherOptions{})
+// CheckReleased checks documentation pages at given URLs or released ones
+// if no URL is given, and writes results to a writer.
+func CheckReleased(w io.Writer, links []string, o DocCheckOptions) error {
+	tlsConfig, err := createTlsConfig(o.CertFile, o.InsecureSSL)
+	if err != nil {
+		return err
+	}
+	f := newDocCheckFetcher(tlsConfig, o)
 
 	failures := make(Failures)
 
 	// has position args
-	for _, arg := range flag.Args() {
-		checkDocPage(arg, f, failures)
+	for _, arg := range links {
+		checkDocPage(w, arg, f, failures)
 	}
-	if len(flag.Args()) > 0 {
-		return
+	if len(links) > 0 {
+		return nil
 	}
 
-	domain := "https://access.redhat.com"
-	_ = domain
 	docBaseUrl := "https://access.redhat.com/documentation/en-us/red_hat_amq/"
 
-	//varsions, err := fetchVersions(f, docBaseUrl)
-	//mustNot(err)
-	//for _, suffix := range varsions {
-	//	s := domain + suffix
-	for _, s := range []string{docBaseUrl} {
-		r, err := f.Fetch(s)
-		a, ok := r.Page()
-		//fmt.Printf("%#v", a.links)
-
-		if r.statusCode != 200 || err != nil || !ok {
-			fmt.Printf("ERROR: %d, %s\n", r.statusCode, err)
-			continue
-		}
+	r, err := f.Fetch(docBaseUrl)
+	a, ok := r.Page()
 
-		for link := range a.links {
-			if !isSinglePageHtmlDocLink(link) {
-				continue
-			}
+	if r.statusCode != 200 || err != nil || !ok {
+		fmt.Fprintf(w, "ERROR: %d, %s\n", r.statusCode, err)
+		return nil
+	}
 
-			checkDocPage(link, f, failures)
+	for link := range a.links {
+		if !isSinglePageHtmlDocLink(link) {
+			continue
 		}
 
-		printFailures(failures)
+		checkDocPage(w, link, f, failures)
 	}
+
+	printFailures(w, failures)
+
+	return nil
 }
 
-func checkDocPage(docPage string, f fetcher, failures Failures) {
-	fmt.Println("* " + docPage)
-	r, err := f.Fetch(docPage)
+func checkDocPage(w io.Writer, docPage string, f fetcher, failures Failures) {
+	fmt.Fprintln(w, "* "+docPage)
+	// Pages are fetched without a cache as ones found as links of others
+	// are compacted there.
+	r, err := f.sendRequestWithRetries(docPage)
 	a, ok := r.Page()
 	if r.statusCode != 200 || err != nil || !ok {
-		fmt.Printf("ERROR: %d, %s %s\n", r.statusCode, docPage, err)
+		fmt.Fprintf(w, "ERROR: %d, %s %s\n", r.statusCode, docPage, err)
 		return
 	}
 	for link, _ := range a.links {
-		//log.Println("fetching " + link)
+		r, err = f.Fetch(link)
 
-		for _, whitelisted := range Whitelist {
-			if strings.HasSuffix(link, whitelisted[0]) {
-				goto skip
+		if r.statusCode != 200 || err != nil {
+			if err == nil {
+				err = newHTTPStatusError(r.statusCode)
 			}
-		}
 
-		r, err = f.Fetch(link)
+			if isWhitelisted(link, err) {
+				continue
+			}
 
-		if r.statusCode != 200 || err != nil {
-			fmt.Printf("**\t%s\n", link)
-			fmt.Printf("***\t%s\n", err)
+			k := errorKindOf(err)
+
+			fmt.Fprintf(w, "**\t%s\n", link)
+			fmt.Fprintf(w, "***\t%s: %s\n", k, err)
 
-			addFailure(failures, docPage, link, err.Error())
+			addFailure(failures, docPage, link, k, err.Error())
 		}
+	}
+}
+
+var outputFormats = map[string]struct{}{
+	"html": {},
+	"json": {},
+	"text": {},
+}
+
+// Main runs muffet with command line arguments and exits a process.
+func Main() {
+	s, err := command(os.Args[1:], os.Stdout, os.Stderr)
 
-	skip:
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+
+	os.Exit(s)
 }
 
-func command(ss []string, w io.Writer) (int, error) {
+// command runs muffet with command line arguments writing results to a writer
+// and warnings and progress of checks to an error writer.
+func command(ss []string, w, ew io.Writer) (int, error) {
 	args, err := getArguments(ss)
 
 	if err != nil {
 		return 0, err
+	} else if args.Serve {
+		fprintln(w, "Listening on", args.ListenAddress)
+		return 0, http.ListenAndServe(args.ListenAddress, newServer())
+	}
+
+	c, err := newCommandChecker(args)
+
+	if err != nil {
+		return 0, err
+	}
+
+	fprintWarnings(ew, c.Warnings())
+
+	if args.MetricsAddress != "" {
+		l, err := net.Listen("tcp", args.MetricsAddress)
+
+		if err != nil {
+			return 0, err
+		}
+
+		m := http.NewServeMux()
+		m.Handle("/metrics", c.Metrics())
+		sv := &http.Server{Handler: m}
+		defer sv.Close()
+
+		go sv.Serve(l)
+	}
+
+	ctx, cancel := newCheckContext(args.MaxDuration)
+	defer cancel()
+
+	go c.Check(ctx)
+
+	if args.Progress {
+		r := newTerminalProgressReporter(ew, c.Statistics)
+		r.Start()
+		defer r.Stop()
+
+		w = r.Writer(w)
+	}
+
+	t := (<-chan time.Time)(nil)
+
+	if args.CheckpointFile != "" {
+		k := time.NewTicker(args.CheckpointInterval)
+		defer k.Stop()
+		t = k.C
 	}
 
-	c, err := newChecker(args.URL, checkerOptions{
+	s := 0
+	rs := []pageResult(nil)
+	save := func() error {
+		return newCheckpoint(args.URL, c.Pages(), rs).Write(args.CheckpointFile)
+	}
+
+	for done := false; !done; {
+		select {
+		case r, ok := <-c.Results():
+			if !ok {
+				done = true
+				break
+			}
+
+			if !args.ByTarget {
+				fprintPageResult(w, r, args)
+			}
+
+			if isFailedPageResult(r, args.WarnedErrorKinds) {
+				s = 1
+			}
+
+			if args.CheckpointFile != "" || args.Format == "html" || args.ByTarget || findsOrphans(args) {
+				rs = append(rs, r)
+			}
+		case <-t:
+			if err := save(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if args.CheckpointFile != "" {
+		if err := save(); err != nil {
+			return 0, err
+		}
+	}
+
+	if args.MetricsFile != "" {
+		b := &bytes.Buffer{}
+
+		if err := c.Metrics().Write(b); err != nil {
+			return 0, err
+		} else if err := writeFileAtomically(args.MetricsFile, b.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Page results of a crawl without ones of a sitemap
+	ps := rs
+
+	// Sitemaps are checked after crawls to find pages missing from them.
+	if args.CheckSitemap && incompleteness(ctx, c) == nil {
+		r, err := c.CheckSitemap(ctx, args.URL)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if !args.ByTarget {
+			fprintPageResult(w, r, args)
+		}
+
+		if isFailedPageResult(r, args.WarnedErrorKinds) {
+			s = 1
+		}
+
+		rs = append(rs, r)
+	}
+
+	if args.ByTarget && args.Format != "html" {
+		fprintTargetResults(w, newTargetResults(rs), args.Format)
+	}
+
+	xs := []orphan(nil)
+
+	// Orphans are not reported for incomplete checks as most pages would be
+	// false positives.
+	if findsOrphans(args) && incompleteness(ctx, c) == nil {
+		us, ws, err := getOrphanInventory(c, args)
+
+		if err != nil {
+			return 0, err
+		}
+
+		fprintWarnings(ew, ws)
+
+		xs = findOrphans(us, c.Pages(), ps)
+
+		if args.Format != "html" {
+			fprintOrphans(w, xs, args.Format)
+		}
+	}
+
+	if args.Format == "html" {
+		r := ""
+
+		if err := incompleteness(ctx, c); err != nil {
+			r = incompleteReason(err)
+		}
+
+		if err := newHTMLReport(args.URL, rs, r).WithOrphans(xs).Write(w); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := incompleteness(ctx, c); err != nil {
+		if args.Format != "html" {
+			fprintIncompleteness(w, args.Format, err)
+		}
+
+		s = 1
+	}
+
+	return s, nil
+}
+
+func newTerminalProgressReporter(w io.Writer, g func() statisticsSnapshot) progressReporter {
+	if f, ok := w.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+		return newProgressReporter(w, true, terminalProgressInterval, g)
+	}
+
+	return newProgressReporter(w, false, logProgressInterval, g)
+}
+
+func newCommandChecker(args arguments) (checker, error) {
+	if args.ResumedFile == "" {
+		return newChecker(args.URL, newCheckerOptions(args))
+	}
+
+	cp, err := readCheckpoint(args.ResumedFile)
+
+	if err != nil {
+		return checker{}, err
+	} else if cp.URL() != args.URL {
+		return checker{}, fmt.Errorf("checkpoint for a different URL: %v", cp.URL())
+	}
+
+	return resumeChecker(args.URL, newCheckerOptions(args), cp)
+}
+
+func fprintPageResult(w io.Writer, r pageResult, args arguments) {
+	if r.OK() && !r.HasWarnings() && !args.Verbose {
+		return
+	}
+
+	switch args.Format {
+	case "html":
+		// Page results are rendered at once into a report after a check.
+	case "json":
+		fprintJSON(w, r)
+	default:
+		fprintln(w, r.String(args.Verbose))
+	}
+}
+
+func fprintTargetResults(w io.Writer, ts []targetResult, f string) {
+	for _, t := range ts {
+		switch f {
+		case "json":
+			fprintJSON(w, t)
+		default:
+			fprintln(w, t.String())
+		}
+	}
+}
+
+func findsOrphans(args arguments) bool {
+	return args.OrphanDirectory != "" || args.OrphansFromSitemap
+}
+
+// getOrphanInventory lists URLs which should be linked from pages. It also
+// returns warnings on sitemaps not found.
+func getOrphanInventory(c checker, args arguments) ([]string, []string, error) {
+	us, ws := []string(nil), []string(nil)
+
+	if args.OrphanDirectory != "" {
+		ss, err := getDirectoryURLs(args.OrphanDirectory, args.URL)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		us = append(us, ss...)
+	}
+
+	if args.OrphansFromSitemap {
+		ss, vs, err := c.SitemapURLs(args.URL)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		us, ws = append(us, ss...), vs
+	}
+
+	return us, ws, nil
+}
+
+func fprintWarnings(w io.Writer, ss []string) {
+	for _, s := range ss {
+		fprintln(w, color.YellowString("Warning: %v", s))
+	}
+}
+
+func fprintOrphans(w io.Writer, xs []orphan, f string) {
+	for _, o := range xs {
+		switch f {
+		case "json":
+			fprintJSON(w, o)
+		default:
+			fprintln(w, o.String())
+		}
+	}
+}
+
+// newCheckContext creates a context canceled after a given duration, if it is
+// positive, or on interruption signals.
+func newCheckContext(d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+
+	if d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-c:
+			stop()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(c)
+	}()
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// incompleteness returns an error which makes a check incomplete.
+func incompleteness(ctx context.Context, c checker) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Err()
+}
+
+// incompleteReason describes why a check is incomplete.
+func incompleteReason(err error) string {
+	switch err {
+	case context.DeadlineExceeded:
+		return "max duration exceeded"
+	case context.Canceled:
+		return "interrupted"
+	}
+
+	return err.Error()
+}
+
+func fprintIncompleteness(w io.Writer, f string, err error) {
+	r := incompleteReason(err)
+
+	switch f {
+	case "json":
+		fprintJSON(w, struct {
+			Incomplete bool   `json:"incomplete"`
+			Reason     string `json:"reason"`
+		}{true, r})
+	default:
+		fprintln(w, color.RedString("Check incomplete: %v; results above are partial", r))
+	}
+}
+
+func newCheckerOptions(args arguments) checkerOptions {
+	return checkerOptions{
 		fetcherOptions{
 			args.Concurrency,
 			args.ExcludedPatterns,
@@ -218,31 +588,44 @@ func command(ss []string, w io.Writer) (int, error) {
 			args.MaxRedirections,
 			args.Timeout,
 			args.OnePageOnly,
+			args.Retries,
+			args.RetriedErrorKinds,
+			args.Soft404TitlePatterns,
+			args.Soft404BodyPatterns,
+			args.Soft404Probe,
+			args.Profile,
+			args.UserAgent,
+			args.HostHeaders,
+			args.MaxHTMLSize,
+			args.MaxBodySize,
+			args.Schemes,
+			args.CheckMX,
+			args.DNSServer,
+			args.DNSCacheTTL,
+			args.DisableDNSCache,
+			args.Resolves,
+			args.DialMode,
 		},
 		args.FollowRobotsTxt,
 		args.FollowSitemapXML,
 		args.SkipTLSVerification,
-	})
-
-	if err != nil {
-		return 0, err
+		args.IgnoredErrorKinds,
+		args.WarnRedirects,
+		args.Sitemaps,
+		args.RobotsTxtUserAgent,
+		args.SkipDisallowedLinks,
 	}
+}
 
-	go c.Check()
-
-	s := 0
-
-	for r := range c.Results() {
-		if !r.OK() || args.Verbose {
-			fprintln(w, r.String(args.Verbose))
-		}
-
-		if !r.OK() {
-			s = 1
+// isFailedPageResult returns true if a page has errors of kinds not warned.
+func isFailedPageResult(r pageResult, wks errorKindSet) bool {
+	for k := range r.ErrorKinds() {
+		if !wks.Contains(k) {
+			return true
 		}
 	}
 
-	return s, nil
+	return false
 }
 
 func fprintln(w io.Writer, xs ...interface{}) {
@@ -250,3 +633,9 @@ func fprintln(w io.Writer, xs ...interface{}) {
 		panic(err)
 	}
 }
+
+func fprintJSON(w io.Writer, x interface{}) {
+	if err := json.NewEncoder(w).Encode(x); err != nil {
+		panic(err)
+	}
+}
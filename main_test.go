@@ -1,44 +1,397 @@
 package muffet
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestCheckListOfLinks(t *testing.T) {
+	d, err := ioutil.TempDir("", "muffet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	assert.Nil(t, os.Mkdir(filepath.Join(d, "foo"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(d, "foo", "index.html"), []byte("<html></html>"), 0644))
+
+	b := &bytes.Buffer{}
+	assert.Nil(t, CheckListOfLinks(b, nil, DocCheckOptions{ServedDirectory: d}))
+	assert.True(t, strings.HasPrefix(b.String(), "* http://127.0.0.1:"))
+	assert.NotContains(t, b.String(), "ERROR")
+}
+
+func TestCheckListOfLinksWithLinkedPages(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		switch r.URL.Path {
+		case "/a":
+			w.Write([]byte(`<a href="/b" />`))
+		case "/b":
+			w.Write([]byte(`<a href="/c" />`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	b := &bytes.Buffer{}
+	assert.Nil(t, CheckListOfLinks(b, []string{s.URL + "/a", s.URL + "/b"}, DocCheckOptions{}))
+	assert.Contains(t, b.String(), "**\t"+s.URL+"/c\n")
+	assert.Contains(t, b.String(), "***\thttp-status: ")
+	assert.NotContains(t, b.String(), "ERROR")
+}
+
+func TestCheckListOfLinksError(t *testing.T) {
+	for _, o := range []DocCheckOptions{
+		{ServedDirectory: "foo/bar"},
+		{CertFile: "foo/bar.crt"},
+		{CertFile: "main.go"},
+	} {
+		assert.NotNil(t, CheckListOfLinks(ioutil.Discard, nil, o))
+	}
+}
+
+func TestCheckReleasedError(t *testing.T) {
+	assert.NotNil(t, CheckReleased(ioutil.Discard, nil, DocCheckOptions{CertFile: "foo/bar.crt"}))
+}
+
 func TestCommand(t *testing.T) {
 	for _, ss := range [][]string{
 		{"-x", rootURL},
 		{"-j", authorizationHeader("me:password"), basicAuthURL},
 		{"-e", ".*", erroneousURL},
+		{"--ignore-error", "http-status", "--ignore-error", "missing-fragment", "--ignore-error", "parse", erroneousURL},
+		{"--warn-error", "http-status", "--warn-error", "missing-fragment", "--warn-error", "parse", erroneousURL},
+		{"--progress", rootURL},
 	} {
-		s, err := command(ss, ioutil.Discard)
+		s, err := command(ss, ioutil.Discard, ioutil.Discard)
 
 		assert.Zero(t, s)
 		assert.Nil(t, err)
 	}
 }
 
+func TestCommandWithProgress(t *testing.T) {
+	b, e := &bytes.Buffer{}, &bytes.Buffer{}
+	s, err := command([]string{"--progress", "--format", "json", rootURL}, b, e)
+
+	assert.Zero(t, s)
+	assert.Nil(t, err)
+	assert.NotContains(t, b.String(), "progress:")
+	assert.True(t, strings.HasPrefix(e.String(), "progress:"))
+}
+
+func TestCommandWithWarnings(t *testing.T) {
+	b, e := &bytes.Buffer{}, &bytes.Buffer{}
+	s, err := command([]string{"--follow-sitemap-xml", "--sitemap", nonExistentURL, rootURL}, b, e)
+
+	assert.Zero(t, s)
+	assert.Nil(t, err)
+	assert.NotContains(t, b.String(), "Warning:")
+	assert.Contains(t, e.String(), "Warning: sitemap not found at "+nonExistentURL)
+}
+
 func TestCommandErroneousResult(t *testing.T) {
 	for _, ss := range [][]string{
 		{erroneousURL},
+		{"--format", "json", erroneousURL},
+		{"--format", "html", erroneousURL},
+		{"--by-target", erroneousURL},
+		{"--by-target", "--format", "json", erroneousURL},
+		{"--warn-error", "http-status", erroneousURL},
 	} {
-		s, err := command(ss, ioutil.Discard)
+		s, err := command(ss, ioutil.Discard, ioutil.Discard)
 
 		assert.Equal(t, 1, s)
 		assert.Nil(t, err)
 	}
 }
 
+func TestCommandWithJSONFormat(t *testing.T) {
+	b := &bytes.Buffer{}
+	s, err := command([]string{"--format", "json", erroneousURL}, b, ioutil.Discard)
+
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+
+	r := struct {
+		URL   string
+		OK    bool
+		Links []struct{ Kind string }
+	}{}
+
+	assert.Nil(t, json.Unmarshal(b.Bytes(), &r))
+	assert.Equal(t, erroneousURL, r.URL)
+	assert.False(t, r.OK)
+
+	ks := map[string]bool{}
+
+	for _, l := range r.Links {
+		ks[l.Kind] = true
+	}
+
+	assert.Equal(t, map[string]bool{"": true, "http-status": true, "missing-fragment": true, "parse": true}, ks)
+}
+
+func TestCommandWithHTMLFormat(t *testing.T) {
+	b := &bytes.Buffer{}
+	s, err := command([]string{"--format", "html", erroneousURL}, b, ioutil.Discard)
+
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(b.String(), "<!DOCTYPE html>"))
+	assert.Contains(t, b.String(), nonExistentURL)
+	assert.NotContains(t, b.String(), "Check incomplete")
+}
+
+func TestCommandWithByTarget(t *testing.T) {
+	b := &bytes.Buffer{}
+	s, err := command([]string{"--by-target", "--format", "json", erroneousURL}, b, ioutil.Discard)
+
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+
+	us := map[string]int{}
+
+	for _, l := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		r := struct {
+			URL       string
+			PageCount int
+			Pages     []string
+		}{}
+
+		assert.Nil(t, json.Unmarshal([]byte(l), &r))
+		assert.Equal(t, []string{erroneousURL}, r.Pages)
+		us[r.URL] = r.PageCount
+	}
+
+	assert.Equal(t, 1, us[nonExistentURL])
+	assert.Equal(t, 1, us[erroneousURL+"#foo"])
+}
+
+func TestCommandWithOrphans(t *testing.T) {
+	d, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	for _, p := range []string{"index.html", "foo", "bar.png"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(d, p), nil, 0644))
+	}
+
+	for _, ss := range [][]string{
+		{"--orphans-from-sitemap", rootURL},
+		{"--orphans-from-directory", d, rootURL},
+		{"--orphans-from-directory", d, "--format", "json", rootURL},
+		{"--orphans-from-directory", d, "--format", "html", rootURL},
+	} {
+		b := &bytes.Buffer{}
+		s, err := command(ss, b, ioutil.Discard)
+
+		assert.Zero(t, s)
+		assert.Nil(t, err)
+
+		if ss[0] == "--orphans-from-sitemap" {
+			assert.Empty(t, b.String())
+		} else {
+			assert.Contains(t, b.String(), rootURL+"/bar.png")
+			assert.NotContains(t, b.String(), rootURL+"/foo")
+			assert.NotContains(t, b.String(), rootURL+"/index.html")
+		}
+	}
+}
+
+func TestCommandWithOrphansError(t *testing.T) {
+	for _, ss := range [][]string{
+		{"--orphans-from-sitemap", "--sitemap", noResponseURL, rootURL},
+		{"--orphans-from-directory", filepath.Join(os.TempDir(), "muffet-nonexistent"), rootURL},
+	} {
+		_, err := command(ss, ioutil.Discard, ioutil.Discard)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCommandWithCheckSitemapError(t *testing.T) {
+	b := &bytes.Buffer{}
+	s, err := command([]string{"--check-sitemap", "--sitemap", noResponseURL + "/sitemap.xml", "--format", "html", rootURL}, b, ioutil.Discard)
+
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), noResponseURL+"/sitemap.xml")
+}
+
+func TestCommandWithCheckSitemap(t *testing.T) {
+	for _, ss := range [][]string{
+		{"--check-sitemap", sitemapCheckURL},
+		{"--check-sitemap", "--format", "json", sitemapCheckURL},
+		{"--check-sitemap", "--by-target", sitemapCheckURL},
+		{"--check-sitemap", "--format", "html", sitemapCheckURL},
+	} {
+		b := &bytes.Buffer{}
+		s, err := command(ss, b, ioutil.Discard)
+
+		assert.Equal(t, 1, s)
+		assert.Nil(t, err)
+		assert.Contains(t, b.String(), sitemapCheckURL+"/image.png")
+	}
+
+	s, err := command([]string{"--check-sitemap", "--ignore-error", "sitemap", "--ignore-error", "http-status", sitemapCheckURL}, ioutil.Discard, ioutil.Discard)
+
+	assert.Zero(t, s)
+	assert.Nil(t, err)
+
+	s, err = command([]string{"--check-sitemap", missingMetadataURL}, ioutil.Discard, ioutil.Discard)
+
+	assert.Zero(t, s)
+	assert.Nil(t, err)
+}
+
+func TestCommandWithMaxDuration(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(2 * time.Second)
+		}
+
+		w.Write([]byte(`<html><body><a href="/slow">slow</a></body></html>`))
+	}))
+	defer s.Close()
+
+	for f, m := range map[string]string{
+		"text": "Check incomplete: max duration exceeded",
+		"json": `{"incomplete":true,"reason":"max duration exceeded"}`,
+		"html": "Check incomplete: max duration exceeded; results below are partial.",
+	} {
+		b := &bytes.Buffer{}
+		c, err := command([]string{"--max-duration", "1", "--format", f, s.URL}, b, ioutil.Discard)
+
+		assert.Equal(t, 1, c)
+		assert.Nil(t, err)
+		assert.Contains(t, b.String(), m)
+	}
+}
+
+func TestNewCheckContext(t *testing.T) {
+	ctx, cancel := newCheckContext(0)
+	assert.Nil(t, ctx.Err())
+
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	ctx, cancel = newCheckContext(time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestNewCheckContextWithSignal(t *testing.T) {
+	ctx, cancel := newCheckContext(0)
+	defer cancel()
+
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestCommandWithCheckpoint(t *testing.T) {
+	d, err := ioutil.TempDir("", "muffet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	f := filepath.Join(d, "checkpoint.json")
+
+	s, err := command([]string{"--checkpoint", f, erroneousURL}, ioutil.Discard, ioutil.Discard)
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+
+	c, err := readCheckpoint(f)
+	assert.Nil(t, err)
+	assert.Equal(t, erroneousURL, c.URL())
+	assert.Equal(t, 1, len(c.Results()))
+	assert.Zero(t, len(c.PendingPages()))
+
+	b := &bytes.Buffer{}
+	s, err = command([]string{"--resume", f, erroneousURL}, b, ioutil.Discard)
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), erroneousURL)
+}
+
+func TestCommandWithCheckpointError(t *testing.T) {
+	_, err := command([]string{"--checkpoint", "/nonexistent/checkpoint.json", rootURL}, ioutil.Discard, ioutil.Discard)
+	assert.NotNil(t, err)
+}
+
+func TestCommandWithResumeError(t *testing.T) {
+	f, err := ioutil.TempFile("", "muffet")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+
+	assert.Nil(t, f.Close())
+	assert.Nil(t, newCheckpoint(rootURL, nil, nil).Write(f.Name()))
+
+	for _, ss := range [][]string{
+		{"--resume", "/nonexistent/checkpoint.json", rootURL},
+		{"--resume", f.Name(), erroneousURL},
+	} {
+		_, err := command(ss, ioutil.Discard, ioutil.Discard)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCommandWithMetricsFile(t *testing.T) {
+	d, err := ioutil.TempDir("", "muffet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	f := filepath.Join(d, "muffet.prom")
+
+	s, err := command([]string{"--metrics-file", f, erroneousURL}, ioutil.Discard, ioutil.Discard)
+	assert.Equal(t, 1, s)
+	assert.Nil(t, err)
+
+	bs, err := ioutil.ReadFile(f)
+	assert.Nil(t, err)
+	assert.Contains(t, string(bs), `muffet_broken_links_total{kind="http-status"}`)
+}
+
+func TestCommandWithMetricsListener(t *testing.T) {
+	s, err := command([]string{"--metrics-listen", "127.0.0.1:0", rootURL}, ioutil.Discard, ioutil.Discard)
+	assert.Zero(t, s)
+	assert.Nil(t, err)
+}
+
+func TestCommandWithMetricsError(t *testing.T) {
+	for _, ss := range [][]string{
+		{"--metrics-listen", "foo", rootURL},
+		{"--metrics-file", "/nonexistent/muffet.prom", rootURL},
+	} {
+		_, err := command(ss, ioutil.Discard, ioutil.Discard)
+		assert.NotNil(t, err)
+	}
+}
+
 func TestCommandError(t *testing.T) {
 	for _, ss := range [][]string{
 		{":"},
 		{"-t", "foo", rootURL},
 		{"-j", authorizationHeader("you:password"), basicAuthURL},
 	} {
-		_, err := command(ss, ioutil.Discard)
+		_, err := command(ss, ioutil.Discard, ioutil.Discard)
 
 		assert.NotNil(t, err)
 	}
@@ -47,3 +400,9 @@ func TestCommandError(t *testing.T) {
 func authorizationHeader(s string) string {
 	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(s))
 }
+
+func TestIncompleteReason(t *testing.T) {
+	assert.Equal(t, "max duration exceeded", incompleteReason(context.DeadlineExceeded))
+	assert.Equal(t, "interrupted", incompleteReason(context.Canceled))
+	assert.Equal(t, "foo", incompleteReason(errors.New("foo")))
+}
@@ -0,0 +1,213 @@
+package muffet
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fetchDurationBuckets are upper bounds of buckets of fetch durations in
+// seconds.
+var fetchDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labelValueReplacer escapes label values as per the Prometheus text format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type requestMetricKey struct {
+	host, class string
+}
+
+// metrics are metrics of a check exported in the Prometheus text format.
+type metrics struct {
+	mutex          *sync.Mutex
+	requests       map[requestMetricKey]int
+	fetchDurations histogram
+	cacheHits      int
+	cacheMisses    int
+	retries        int
+	pagesChecked   int
+	linksChecked   int
+	brokenLinks    map[ErrorKind]int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		mutex:          &sync.Mutex{},
+		requests:       map[requestMetricKey]int{},
+		fetchDurations: newHistogram(fetchDurationBuckets),
+		brokenLinks:    map[ErrorKind]int{},
+	}
+}
+
+// AddRequest counts an HTTP request to a host. A status code of 0 means
+// that no response is received.
+func (m *metrics) AddRequest(h string, s int) {
+	c := "error"
+
+	if s != 0 {
+		c = fmt.Sprintf("%vxx", s/100)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests[requestMetricKey{h, c}]++
+}
+
+// ObserveFetch observes a duration of a fetch started at a given time.
+func (m *metrics) ObserveFetch(t time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.fetchDurations.Observe(time.Since(t).Seconds())
+}
+
+func (m *metrics) AddCacheLookup(hit bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+func (m *metrics) AddRetry() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.retries++
+}
+
+// AddResult counts a checked page, its links and broken ones by error kinds.
+func (m *metrics) AddResult(r pageResult) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.pagesChecked++
+	m.linksChecked += len(r.Links())
+
+	for _, l := range r.Links() {
+		if !l.OK() && !l.Ignored() {
+			m.brokenLinks[l.ErrorKind()]++
+		}
+	}
+}
+
+// Write writes metrics in the Prometheus text format.
+func (m *metrics) Write(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ss := []string{}
+	add := func(n, t, h string, ls ...string) {
+		ss = append(ss, fmt.Sprintf("# HELP %v %v", n, h), fmt.Sprintf("# TYPE %v %v", n, t))
+		ss = append(ss, ls...)
+	}
+
+	ls := []string{}
+
+	for k, v := range m.requests {
+		ls = append(ls, formatMetric("muffet_requests_total", v, "host", k.host, "class", k.class))
+	}
+
+	sort.Strings(ls)
+	add("muffet_requests_total", "counter", "HTTP requests by host and status class.", ls...)
+
+	add(
+		"muffet_fetch_duration_seconds",
+		"histogram",
+		"Durations of fetches including redirections.",
+		m.fetchDurations.Format("muffet_fetch_duration_seconds")...)
+	add("muffet_cache_hits_total", "counter", "Fetches served from caches.",
+		formatMetric("muffet_cache_hits_total", m.cacheHits))
+	add("muffet_cache_misses_total", "counter", "Fetches not served from caches.",
+		formatMetric("muffet_cache_misses_total", m.cacheMisses))
+	add("muffet_retries_total", "counter", "Retried requests.",
+		formatMetric("muffet_retries_total", m.retries))
+	add("muffet_pages_checked_total", "counter", "Checked pages.",
+		formatMetric("muffet_pages_checked_total", m.pagesChecked))
+	add("muffet_links_checked_total", "counter", "Checked links.",
+		formatMetric("muffet_links_checked_total", m.linksChecked))
+
+	ls = []string{}
+
+	for k, v := range m.brokenLinks {
+		ls = append(ls, formatMetric("muffet_broken_links_total", v, "kind", string(k)))
+	}
+
+	sort.Strings(ls)
+	add("muffet_broken_links_total", "counter", "Broken links by error kinds.", ls...)
+
+	_, err := io.WriteString(w, strings.Join(ss, "\n")+"\n")
+	return err
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	// Errors on writes are ignored as clients might have gone.
+	m.Write(w)
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []int
+	sum     float64
+	count   int
+}
+
+func newHistogram(bs []float64) histogram {
+	return histogram{bs, make([]int, len(bs)), 0, 0}
+}
+
+// Observe observes a value. It must be called with a lock held.
+func (h *histogram) Observe(x float64) {
+	for i, b := range h.buckets {
+		if x <= b {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += x
+	h.count++
+}
+
+func (h histogram) Format(n string) []string {
+	ss := make([]string, 0, len(h.buckets)+3)
+
+	for i, b := range h.buckets {
+		ss = append(ss, formatMetric(n+"_bucket", h.counts[i], "le", formatFloat(b)))
+	}
+
+	return append(
+		ss,
+		formatMetric(n+"_bucket", h.count, "le", "+Inf"),
+		n+"_sum "+formatFloat(h.sum),
+		formatMetric(n+"_count", h.count))
+}
+
+// formatMetric formats a sample with pairs of label names and values.
+func formatMetric(n string, v int, ls ...string) string {
+	if len(ls) == 0 {
+		return fmt.Sprintf("%v %v", n, v)
+	}
+
+	ss := make([]string, 0, len(ls)/2)
+
+	for i := 0; i < len(ls); i += 2 {
+		ss = append(ss, fmt.Sprintf(`%v="%v"`, ls[i], labelValueReplacer.Replace(ls[i+1])))
+	}
+
+	return fmt.Sprintf("%v{%v} %v", n, strings.Join(ss, ","), v)
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
@@ -0,0 +1,87 @@
+package muffet
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+	m.AddRequest("foo.com", 200)
+	m.AddRequest("foo.com", 204)
+	m.AddRequest("foo.com", 404)
+	m.AddRequest("bar.com", 0)
+	m.ObserveFetch(time.Now())
+	m.AddCacheLookup(true)
+	m.AddCacheLookup(false)
+	m.AddCacheLookup(false)
+	m.AddRetry()
+	m.AddResult(newPageResult("foo", []linkResult{
+		newLinkResult("bar", 200, nil),
+		newLinkResult("baz", 0, newHTTPStatusError(404)),
+		newLinkResult("qux", 0, errors.New("qux")).Ignore(),
+	}))
+
+	b := &bytes.Buffer{}
+	assert.Nil(t, m.Write(b))
+
+	for _, s := range []string{
+		"# TYPE muffet_requests_total counter",
+		`muffet_requests_total{host="bar.com",class="error"} 1`,
+		`muffet_requests_total{host="foo.com",class="2xx"} 2`,
+		`muffet_requests_total{host="foo.com",class="4xx"} 1`,
+		"# TYPE muffet_fetch_duration_seconds histogram",
+		`muffet_fetch_duration_seconds_bucket{le="10"} 1`,
+		`muffet_fetch_duration_seconds_bucket{le="+Inf"} 1`,
+		"muffet_fetch_duration_seconds_count 1",
+		"muffet_cache_hits_total 1",
+		"muffet_cache_misses_total 2",
+		"muffet_retries_total 1",
+		"muffet_pages_checked_total 1",
+		"muffet_links_checked_total 3",
+		`muffet_broken_links_total{kind="http-status"} 1`,
+	} {
+		assert.Contains(t, b.String(), s+"\n")
+	}
+
+	assert.NotContains(t, b.String(), `kind="other"`)
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	w := httptest.NewRecorder()
+	newMetrics().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "# HELP muffet_requests_total"))
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{1, 2})
+	h.Observe(0.5)
+	h.Observe(1.5)
+	h.Observe(3)
+
+	assert.Equal(
+		t,
+		[]string{
+			`foo_bucket{le="1"} 1`,
+			`foo_bucket{le="2"} 2`,
+			`foo_bucket{le="+Inf"} 3`,
+			"foo_sum 5",
+			"foo_count 3",
+		},
+		h.Format("foo"))
+}
+
+func TestFormatMetric(t *testing.T) {
+	assert.Equal(t, "foo 42", formatMetric("foo", 42))
+	assert.Equal(t, `foo{bar="baz",qux="a\\b\"c\nd"} 42`, formatMetric("foo", 42, "bar", "baz", "qux", "a\\b\"c\nd"))
+}
@@ -0,0 +1,41 @@
+// Package muffet provides a website link checker which scrapes and inspects
+// all pages in a website recursively.
+package muffet
+
+import "context"
+
+// Checker checks links in a website.
+type Checker struct {
+	checker checker
+}
+
+// NewChecker creates a checker for a website at a given URL. It fetches the
+// page at the URL and returns an error if it is not available.
+func NewChecker(u string, o Options) (Checker, error) {
+	c, err := newChecker(u, o.checkerOptions())
+
+	if err != nil {
+		return Checker{}, err
+	}
+
+	return Checker{c}, nil
+}
+
+// Check checks pages and calls a given function with a result of each page
+// until all of them are checked or a context is canceled. The function is
+// called sequentially in the current goroutine. It returns an error of the
+// context if it is canceled or one which makes the check incomplete otherwise.
+// A checker can be used only once.
+func (c Checker) Check(ctx context.Context, f func(Result)) error {
+	go c.checker.Check(ctx)
+
+	for r := range c.checker.Results() {
+		f(Result{r})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.checker.Err()
+}
@@ -0,0 +1,42 @@
+package muffet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPublicChecker(t *testing.T) {
+	_, err := NewChecker(rootURL, Options{})
+	assert.Nil(t, err)
+}
+
+func TestNewPublicCheckerError(t *testing.T) {
+	_, err := NewChecker(nonExistentURL, Options{})
+	assert.NotNil(t, err)
+}
+
+func TestPublicCheckerCheck(t *testing.T) {
+	c, err := NewChecker(rootURL, Options{})
+	assert.Nil(t, err)
+
+	us := []string{}
+
+	assert.Nil(t, c.Check(context.Background(), func(r Result) {
+		assert.True(t, r.OK())
+		us = append(us, r.URL())
+	}))
+
+	assert.NotZero(t, len(us))
+}
+
+func TestPublicCheckerCheckWithCanceledContext(t *testing.T) {
+	c, err := NewChecker(erroneousURL, Options{})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, c.Check(ctx, func(Result) {}))
+}
@@ -0,0 +1,147 @@
+package muffet
+
+import (
+	"regexp"
+	"time"
+)
+
+// Options are options of a checker. Zero values mean defaults.
+type Options struct {
+	// Concurrency is a rough maximum number of concurrent HTTP connections.
+	Concurrency int
+	// ExcludedPatterns are patterns of URLs not to be checked.
+	ExcludedPatterns []*regexp.Regexp
+	// Headers are custom headers sent with requests.
+	Headers map[string]string
+	// HostHeaders are custom headers sent with requests to hosts. They take
+	// precedence over Headers.
+	HostHeaders map[string]map[string]string
+	// Profile is a name of a set of headers sent with requests, which is
+	// "default" or "browser".
+	Profile string
+	// MaxHTMLSize is a maximum size of HTML pages in bytes.
+	MaxHTMLSize int
+	// MaxBodySize is a maximum size of non-HTML responses read in bytes.
+	// Their bodies are never checked and links to larger ones are still valid.
+	MaxBodySize int
+	// Schemes are schemes of links checked, which are "http", "https",
+	// "mailto", "tel", "ftp" and "file". They default to all but the last two.
+	Schemes []string
+	// CheckMX makes a checker check MX records of domains in mailto links.
+	CheckMX bool
+	// DNSServer is an address of a DNS server used instead of the system one.
+	DNSServer string
+	// DNSCacheTTL is a duration for which addresses of hosts are cached. It
+	// defaults to a minute.
+	DNSCacheTTL time.Duration
+	// DisableDNSCache makes a checker look up addresses of hosts every time.
+	DisableDNSCache bool
+	// Resolves are addresses used for hosts and ports instead of ones
+	// resolved with DNS, like curl's --resolve. Keys are in "<host>:<port>"
+	// format with lowercase hosts.
+	Resolves map[string]string
+	// DialMode is a mode of dialing hosts, which is "ipv4", "ipv6", "dual"
+	// or "both". It defaults to "dual", where IPv4 and IPv6 addresses are
+	// dialed with Happy Eyeballs. In the "both" mode, links are also requested
+	// over each of them and reported as errors if their hosts respond
+	// differently.
+	DialMode string
+	// UserAgent is a value of User-Agent headers.
+	UserAgent       string
+	IgnoreFragments bool
+	MaxRedirections int
+	// Timeout is a timeout of each HTTP request.
+	Timeout time.Duration
+	// OnePageOnly makes a checker check only links in a given page.
+	OnePageOnly bool
+	// Retries is a number of retries of failed requests.
+	Retries int
+	// RetriedErrorKinds are kinds of errors on which requests are retried.
+	RetriedErrorKinds []ErrorKind
+	FollowRobotsTxt,
+	FollowSitemapXML,
+	SkipTLSVerification bool
+	// IgnoredErrorKinds are kinds of errors which do not make pages erroneous.
+	IgnoredErrorKinds []ErrorKind
+	// WarnRedirects makes a checker warn about permanent, insecure and
+	// cross-host redirects.
+	WarnRedirects bool
+	// Soft404TitlePatterns and Soft404BodyPatterns are patterns of titles
+	// and texts of soft 404 pages.
+	Soft404TitlePatterns,
+	Soft404BodyPatterns []Soft404Pattern
+	// Soft404Probe makes a checker detect pages similar to pages for
+	// nonexistent URLs as soft 404 pages.
+	Soft404Probe bool
+	// Sitemaps are URLs of sitemaps used instead of ones declared in
+	// robots.txt or at /sitemap.xml.
+	Sitemaps []string
+	// RobotsTxtUserAgent is a user agent matched with groups in robots.txt.
+	// It defaults to "muffet".
+	RobotsTxtUserAgent string
+	// SkipDisallowedLinks makes a checker skip links disallowed by robots.txt
+	// of their hosts.
+	SkipDisallowedLinks bool
+}
+
+// Soft404Pattern is a pattern of soft 404 pages on a host. A host of "*"
+// matches any hosts.
+type Soft404Pattern struct {
+	Host   string
+	Regexp *regexp.Regexp
+}
+
+func (o Options) checkerOptions() checkerOptions {
+	rks := errorKindSet(nil)
+
+	if o.RetriedErrorKinds != nil {
+		rks = newErrorKindSet(o.RetriedErrorKinds...)
+	}
+
+	return checkerOptions{
+		fetcherOptions{
+			o.Concurrency,
+			o.ExcludedPatterns,
+			o.Headers,
+			o.IgnoreFragments,
+			o.MaxRedirections,
+			o.Timeout,
+			o.OnePageOnly,
+			o.Retries,
+			rks,
+			newSoft404Patterns(o.Soft404TitlePatterns),
+			newSoft404Patterns(o.Soft404BodyPatterns),
+			o.Soft404Probe,
+			o.Profile,
+			o.UserAgent,
+			o.HostHeaders,
+			o.MaxHTMLSize,
+			o.MaxBodySize,
+			o.Schemes,
+			o.CheckMX,
+			o.DNSServer,
+			o.DNSCacheTTL,
+			o.DisableDNSCache,
+			o.Resolves,
+			o.DialMode,
+		},
+		o.FollowRobotsTxt,
+		o.FollowSitemapXML,
+		o.SkipTLSVerification,
+		newErrorKindSet(o.IgnoredErrorKinds...),
+		o.WarnRedirects,
+		o.Sitemaps,
+		o.RobotsTxtUserAgent,
+		o.SkipDisallowedLinks,
+	}
+}
+
+func newSoft404Patterns(ps []Soft404Pattern) []soft404Pattern {
+	qs := make([]soft404Pattern, 0, len(ps))
+
+	for _, p := range ps {
+		qs = append(qs, newSoft404Pattern(p.Host, p.Regexp))
+	}
+
+	return qs
+}
@@ -0,0 +1,26 @@
+package muffet
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsCheckerOptions(t *testing.T) {
+	o := Options{
+		IgnoredErrorKinds:    []ErrorKind{ErrorKindDNS},
+		Soft404TitlePatterns: []Soft404Pattern{{"*", regexp.MustCompile("foo")}},
+	}.checkerOptions()
+
+	assert.Nil(t, o.RetriedErrorKinds)
+	assert.Equal(t, newErrorKindSet(ErrorKindDNS), o.IgnoredErrorKinds)
+	assert.Equal(t, []soft404Pattern{newSoft404Pattern("*", regexp.MustCompile("foo"))}, o.Soft404TitlePatterns)
+}
+
+func TestOptionsCheckerOptionsWithRetriedErrorKinds(t *testing.T) {
+	o := Options{RetriedErrorKinds: []ErrorKind{}}.checkerOptions()
+	o.Initialize()
+
+	assert.Equal(t, errorKindSet{}, o.RetriedErrorKinds)
+}
@@ -0,0 +1,206 @@
+package muffet
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var pageExtensions = map[string]struct{}{
+	"":       {},
+	".htm":   {},
+	".html":  {},
+	".xhtml": {},
+}
+
+var imageExtensions = map[string]struct{}{
+	".avif": {},
+	".bmp":  {},
+	".gif":  {},
+	".ico":  {},
+	".jpeg": {},
+	".jpg":  {},
+	".png":  {},
+	".svg":  {},
+	".webp": {},
+}
+
+// orphan is a page or an asset which is never linked from pages reachable
+// from a root page.
+type orphan struct {
+	url string
+}
+
+func newOrphan(u string) orphan {
+	return orphan{u}
+}
+
+func (o orphan) URL() string {
+	return o.url
+}
+
+// Kind returns a kind of an orphan guessed from its extension, which is
+// "page", "image" or "asset".
+func (o orphan) Kind() string {
+	p := o.url
+
+	if u, err := url.Parse(o.url); err == nil {
+		p = u.Path
+	}
+
+	if strings.HasSuffix(p, "/") {
+		return "page"
+	}
+
+	e := strings.ToLower(path.Ext(p))
+
+	if _, ok := pageExtensions[e]; ok {
+		return "page"
+	} else if _, ok := imageExtensions[e]; ok {
+		return "image"
+	}
+
+	return "asset"
+}
+
+func (o orphan) String() string {
+	return color.YellowString("orphan %v", o.Kind()) + "\t" + o.url
+}
+
+func (o orphan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		URL  string `json:"orphan"`
+		Kind string `json:"kind"`
+	}{o.url, o.Kind()})
+}
+
+// findOrphans finds URLs in an inventory which are neither pages found in a
+// check nor links in its page results.
+func findOrphans(us []string, ps []string, rs []pageResult) []orphan {
+	m := map[string]struct{}{}
+
+	for _, p := range ps {
+		m[normalizeResourceURL(p)] = struct{}{}
+	}
+
+	for _, r := range rs {
+		for _, l := range r.Links() {
+			m[normalizeResourceURL(l.URL())] = struct{}{}
+		}
+	}
+
+	xs := []orphan{}
+	ds := map[string]struct{}{}
+
+	for _, u := range us {
+		v := normalizeResourceURL(u)
+
+		if _, ok := ds[v]; ok {
+			continue
+		}
+
+		ds[v] = struct{}{}
+
+		if _, ok := m[v]; ok {
+			continue
+		} else if d, ok := indexDirectoryURL(v); ok {
+			if _, ok := m[d]; ok {
+				continue
+			}
+		}
+
+		xs = append(xs, newOrphan(u))
+	}
+
+	sort.Slice(xs, func(i, j int) bool {
+		return xs[i].url < xs[j].url
+	})
+
+	return xs
+}
+
+// normalizeResourceURL drops parts of a URL not identifying a resource.
+func normalizeResourceURL(s string) string {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return s
+	}
+
+	u.Fragment = ""
+	u.RawQuery = ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
+
+// indexDirectoryURL returns a URL of a directory whose index is at a URL.
+func indexDirectoryURL(s string) (string, bool) {
+	for _, i := range []string{"index.html", "index.htm"} {
+		if strings.HasSuffix(s, "/"+i) {
+			return strings.TrimSuffix(s, i), true
+		}
+	}
+
+	return "", false
+}
+
+// getDirectoryURLs lists URLs of files in a directory served at a base URL.
+// Hidden files and directories are skipped.
+func getDirectoryURLs(d string, s string) ([]string, error) {
+	b, err := url.Parse(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.Fragment = ""
+	b.RawQuery = ""
+
+	if b.Path == "" {
+		b.Path = "/"
+	} else if !strings.HasSuffix(b.Path, "/") {
+		b.Path = strings.TrimSuffix(path.Dir(b.Path), "/") + "/"
+	}
+
+	us := []string{}
+
+	err = filepath.Walk(d, func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if p != d && strings.HasPrefix(i.Name(), ".") {
+			if i.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		} else if i.IsDir() {
+			return nil
+		}
+
+		r, err := filepath.Rel(d, p)
+
+		if err != nil {
+			return err
+		}
+
+		us = append(us, b.ResolveReference(&url.URL{Path: filepath.ToSlash(r)}).String())
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return us, nil
+}
@@ -0,0 +1,105 @@
+package muffet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphanKind(t *testing.T) {
+	for _, c := range []struct {
+		url  string
+		kind string
+	}{
+		{"https://foo.com", "page"},
+		{"https://foo.com/", "page"},
+		{"https://foo.com/foo", "page"},
+		{"https://foo.com/foo.html", "page"},
+		{"https://foo.com/foo.HTM?bar", "page"},
+		{"https://foo.com/foo.png", "image"},
+		{"https://foo.com/foo.svg#bar", "image"},
+		{"https://foo.com/foo.css", "asset"},
+		{"https://foo.com/foo.pdf", "asset"},
+	} {
+		assert.Equal(t, c.kind, newOrphan(c.url).Kind())
+	}
+}
+
+func TestOrphanString(t *testing.T) {
+	assert.Contains(t, newOrphan("https://foo.com/foo.png").String(), "orphan image")
+}
+
+func TestOrphanMarshalJSON(t *testing.T) {
+	bs, err := json.Marshal(newOrphan("https://foo.com/foo.png"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"orphan":"https://foo.com/foo.png","kind":"image"}`, string(bs))
+}
+
+func TestFindOrphans(t *testing.T) {
+	xs := findOrphans(
+		[]string{
+			"https://foo.com",
+			"https://foo.com/foo",
+			"https://foo.com/bar.png",
+			"https://foo.com/bar.png",
+			"https://foo.com/baz/index.html",
+			"https://foo.com/qux/index.html",
+			"https://foo.com/quux",
+		},
+		[]string{"https://foo.com/", "https://foo.com/baz/"},
+		[]pageResult{
+			newPageResult("https://foo.com/", []linkResult{
+				newLinkResult("https://foo.com/foo#bar", 200, nil),
+				newLinkResult("https://foo.com/quux?foo=bar", 404, newHTTPStatusError(404)),
+			}),
+		},
+	)
+
+	assert.Equal(t, []orphan{
+		newOrphan("https://foo.com/bar.png"),
+		newOrphan("https://foo.com/qux/index.html"),
+	}, xs)
+}
+
+func TestFindOrphansWithoutInventory(t *testing.T) {
+	assert.Equal(t, []orphan{}, findOrphans(nil, []string{"https://foo.com/"}, nil))
+}
+
+func TestGetDirectoryURLs(t *testing.T) {
+	d, err := ioutil.TempDir("", "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(d)
+
+	for _, p := range []string{"index.html", "foo/bar baz.png", ".git/config", ".hidden"} {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(d, p)), 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(d, p), nil, 0644))
+	}
+
+	for _, c := range []struct {
+		url  string
+		urls []string
+	}{
+		{"https://foo.com", []string{"https://foo.com/foo/bar%20baz.png", "https://foo.com/index.html"}},
+		{"https://foo.com/docs/", []string{"https://foo.com/docs/foo/bar%20baz.png", "https://foo.com/docs/index.html"}},
+		{"https://foo.com/docs/index.html?foo#bar", []string{"https://foo.com/docs/foo/bar%20baz.png", "https://foo.com/docs/index.html"}},
+		{"https://foo.com/index.html", []string{"https://foo.com/foo/bar%20baz.png", "https://foo.com/index.html"}},
+	} {
+		us, err := getDirectoryURLs(d, c.url)
+
+		assert.Nil(t, err)
+		assert.Equal(t, c.urls, us)
+	}
+}
+
+func TestGetDirectoryURLsError(t *testing.T) {
+	_, err := getDirectoryURLs("foo", ":")
+	assert.NotNil(t, err)
+
+	_, err = getDirectoryURLs(filepath.Join(os.TempDir(), "muffet-nonexistent"), "https://foo.com")
+	assert.NotNil(t, err)
+}
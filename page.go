@@ -1,20 +1,26 @@
 package muffet
 
 import (
+	"bytes"
+	"io"
 	"net/url"
-
-	"github.com/yhat/scrape"
-	"golang.org/x/net/html"
-	"golang.org/x/net/html/atom"
 )
 
 type page struct {
-	url   *url.URL
-	ids   map[string]struct{}
-	links map[string]error
+	url     *url.URL
+	ids     idSet
+	links   map[string]error
+	sources map[string][]linkSource
+}
+
+// newPage creates a page from its HTML source.
+func newPage(s string, bs []byte, sc scraper) (*page, error) {
+	return readPage(s, bytes.NewReader(bs), sc)
 }
 
-func newPage(s string, n *html.Node, sc scraper) (*page, error) {
+// readPage creates a page from its HTML source read from a reader. It fails if
+// the source fails to be read.
+func readPage(s string, r io.Reader, sc scraper) (*page, error) {
 	u, err := url.Parse(s)
 
 	if err != nil {
@@ -24,48 +30,50 @@ func newPage(s string, n *html.Node, sc scraper) (*page, error) {
 	u.Fragment = ""
 	u.RawQuery = ""
 
-	ids := map[string]struct{}{}
+	d := scanHTML(r)
 
-	// 6.7.9. Navigating to a fragment
-	// http://w3c.github.io/html/browsers.html#navigating-to-a-fragment-identifier
-	scrape.FindAllNested(n, func(n *html.Node) bool {
-		if s := scrape.Attr(n, "id"); s != "" {
-			ids[s] = struct{}{}
-		}
-		if n.Data == "a" {
-			if s := scrape.Attr(n, "name"); s != "" {
-				ids[s] = struct{}{}
-			}
-		}
-
-		return false
-	})
+	if d.err != nil {
+		return nil, d.err
+	}
 
 	b := u
 
-	if n, ok := scrape.Find(n, func(n *html.Node) bool {
-		return n.DataAtom == atom.Base
-	}); ok {
-		u, err := url.Parse(scrape.Attr(n, "href"))
+	if d.base != "" {
+		v, err := url.Parse(d.base)
 
 		if err != nil {
 			return nil, err
 		}
 
-		b = b.ResolveReference(u)
+		b = b.ResolveReference(v)
 	}
 
-	return &page{u, ids, sc.Scrape(n, b)}, nil
+	return &page{u, d.ids, sc.Scrape(d.sources, b), sc.Sources(d.sources, b)}, nil
 }
 
 func (p page) URL() *url.URL {
 	return p.url
 }
 
-func (p page) IDs() map[string]struct{} {
+func (p page) IDs() idSet {
 	return p.ids
 }
 
 func (p page) Links() map[string]error {
 	return p.links
 }
+
+// Sources returns locations of links in a source keyed by their URLs.
+func (p page) Sources() map[string][]linkSource {
+	return p.sources
+}
+
+// Compact returns a page without links to be kept in a cache.
+func (p page) Compact() *page {
+	return &page{p.url, p.ids, nil, nil}
+}
+
+// Compacted returns true if links of a page are dropped.
+func (p page) Compacted() bool {
+	return p.links == nil
+}
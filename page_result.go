@@ -1,6 +1,7 @@
 package muffet
 
 import (
+	"encoding/json"
 	"sort"
 	"strings"
 
@@ -8,32 +9,103 @@ import (
 )
 
 type pageResult struct {
-	url                            string
-	successMessages, errorMessages []string
+	url   string
+	links []linkResult
 }
 
-func newPageResult(u string, ss, es []string) pageResult {
-	return pageResult{u, ss, es}
+func newPageResult(u string, ls []linkResult) pageResult {
+	return pageResult{u, ls}
+}
+
+func (r pageResult) URL() string {
+	return r.url
+}
+
+func (r pageResult) Links() []linkResult {
+	return r.links
 }
 
 func (r pageResult) OK() bool {
-	return len(r.errorMessages) == 0
+	for _, l := range r.links {
+		if !l.OK() && !l.Ignored() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrorKinds returns kinds of errors which make the page erroneous.
+func (r pageResult) ErrorKinds() errorKindSet {
+	ks := errorKindSet{}
+
+	for _, l := range r.links {
+		if !l.OK() && !l.Ignored() {
+			ks[l.ErrorKind()] = struct{}{}
+		}
+	}
+
+	return ks
+}
+
+func (r pageResult) HasWarnings() bool {
+	for _, l := range r.links {
+		if len(l.Warnings()) != 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (r pageResult) String(v bool) string {
-	ss := []string(nil)
+	ss, ws, es := []string(nil), []string(nil), []string(nil)
 
-	if v {
-		ss = formatMessages(r.successMessages)
+	for _, l := range r.links {
+		if l.OK() {
+			ss = append(ss, l.String())
+		} else if !l.Ignored() {
+			es = append(es, l.String())
+		}
+
+		ws = append(ws, l.WarningStrings()...)
+	}
+
+	if !v {
+		ss = nil
 	}
 
 	return strings.Join(
-		append(append([]string{color.YellowString(r.url)},
-			ss...),
-			formatMessages(r.errorMessages)...),
+		append(append(append([]string{color.YellowString(r.url)},
+			formatMessages(ss)...),
+			formatMessages(ws)...),
+			formatMessages(es)...),
 		"\n")
 }
 
+func (r pageResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		URL   string       `json:"url"`
+		OK    bool         `json:"ok"`
+		Links []linkResult `json:"links"`
+	}{r.url, r.OK(), r.links})
+}
+
+func (r *pageResult) UnmarshalJSON(bs []byte) error {
+	j := struct {
+		URL   string       `json:"url"`
+		Links []linkResult `json:"links"`
+	}{}
+
+	if err := json.Unmarshal(bs, &j); err != nil {
+		return err
+	}
+
+	*r = newPageResult(j.URL, j.Links)
+
+	return nil
+}
+
 func formatMessages(ss []string) []string {
 	ts := make([]string, 0, len(ss))
 
@@ -1,6 +1,8 @@
 package muffet
 
 import (
+	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 
@@ -8,16 +10,53 @@ import (
 )
 
 func TestNewPageResult(t *testing.T) {
-	newPageResult("https://foo.com", nil, nil)
+	newPageResult("https://foo.com", nil)
 }
 
 func TestPageResultOK(t *testing.T) {
-	assert.True(t, newPageResult("https://foo.com", nil, nil).OK())
-	assert.False(t, newPageResult("https://foo.com", nil, []string{"Oh, no!"}).OK())
+	assert.True(t, newPageResult("https://foo.com", nil).OK())
+	assert.False(t, newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 0, errors.New("Oh, no!")),
+	}).OK())
+	assert.True(t, newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 0, errors.New("Oh, no!")).Ignore(),
+	}).OK())
+}
+
+func TestPageResultErrorKinds(t *testing.T) {
+	assert.Equal(t, errorKindSet{}, newPageResult("https://foo.com", nil).ErrorKinds())
+
+	assert.Equal(t, newErrorKindSet(ErrorKindHTTPStatus), newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 200, nil),
+		newLinkResult("https://baz.com", 0, newHTTPStatusError(404)),
+		newLinkResult("https://qux.com", 0, newFetchError(ErrorKindTimeout, errors.New("timeout"))).Ignore(),
+	}).ErrorKinds())
+}
+
+func TestPageResultHasWarnings(t *testing.T) {
+	assert.False(t, newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 200, nil),
+	}).HasWarnings())
+	assert.True(t, newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 200, nil).Warn("baz"),
+	}).HasWarnings())
+}
+
+func TestPageResultStringWithWarnings(t *testing.T) {
+	s := newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 200, nil).Warn("baz"),
+	}).String(false)
+
+	assert.Equal(t, 1, strings.Count(s, "\n"))
+	assert.True(t, strings.Contains(s, "baz"))
 }
 
 func TestPageResultString(t *testing.T) {
-	r := newPageResult("https://foo.com", []string{"foo"}, []string{"bar"})
+	r := newPageResult("https://foo.com", []linkResult{
+		newLinkResult("foo", 200, nil),
+		newLinkResult("bar", 0, errors.New("bar")),
+		newLinkResult("baz", 0, errors.New("baz")).Ignore(),
+	})
 	qs := r.String(false)
 	vs := r.String(true)
 
@@ -26,4 +65,32 @@ func TestPageResultString(t *testing.T) {
 
 	assert.True(t, strings.Contains(qs, "bar"))
 	assert.True(t, strings.Contains(vs, "foo") && strings.Contains(vs, "bar"))
+	assert.False(t, strings.Contains(vs, "baz"))
+}
+
+func TestPageResultMarshalJSON(t *testing.T) {
+	bs, err := json.Marshal(newPageResult("https://foo.com", []linkResult{
+		newLinkResult("https://bar.com", 0, newHTTPStatusError(404)),
+	}))
+
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		`{"url":"https://foo.com","ok":false,"links":[{"url":"https://bar.com","status":404,"error":"404","kind":"http-status"}]}`,
+		string(bs))
+}
+
+func TestPageResultUnmarshalJSON(t *testing.T) {
+	r := pageResult{}
+
+	assert.Nil(t, json.Unmarshal(
+		[]byte(`{"url":"https://foo.com","ok":false,"links":[{"url":"https://bar.com","status":404,"error":"404","kind":"http-status"}]}`),
+		&r))
+	assert.Equal(t, "https://foo.com", r.URL())
+	assert.False(t, r.OK())
+	assert.Equal(t, ErrorKindHTTPStatus, r.Links()[0].ErrorKind())
+}
+
+func TestPageResultUnmarshalJSONError(t *testing.T) {
+	assert.NotNil(t, json.Unmarshal([]byte(`{"url":0}`), &pageResult{}))
 }
@@ -2,20 +2,18 @@ package muffet
 
 import (
 	"net/url"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/net/html"
 )
 
 func TestNewPage(t *testing.T) {
-	_, err := newPage("https://foo.com", dummyHTML(t), newScraper(nil))
+	_, err := newPage("https://foo.com", nil, newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 }
 
 func TestNewPageError(t *testing.T) {
-	_, err := newPage(":", dummyHTML(t), newScraper(nil))
+	_, err := newPage(":", nil, newScraper(nil, defaultSchemes))
 	assert.NotNil(t, err)
 }
 
@@ -24,30 +22,40 @@ func TestPageURL(t *testing.T) {
 	u, err := url.Parse(s)
 	assert.Nil(t, err)
 
-	p, err := newPage(s, dummyHTML(t), newScraper(nil))
+	p, err := newPage(s, nil, newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 
 	assert.Equal(t, u, p.URL())
 }
 
 func TestPageURLWithBaseTag(t *testing.T) {
-	n, err := html.Parse(strings.NewReader(`<base href="_blank" />`))
-	assert.Nil(t, err)
-
-	p, err := newPage("https://foo.com", n, newScraper(nil))
+	p, err := newPage("https://foo.com", []byte(`<base href="_blank" />`), newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 
 	assert.Equal(t, "https://foo.com", p.URL().String())
 }
 
 func TestPageIDs(t *testing.T) {
-	n, err := html.Parse(strings.NewReader(`<p id="foo">Hello!</p>`))
+	p, err := newPage("https://foo.com", []byte(`<p id="foo">Hello!</p>`), newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
 
-	p, err := newPage("https://foo.com", n, newScraper(nil))
+	assert.Equal(t, 1, len(p.IDs()))
+	assert.True(t, p.IDs().Contains("foo"))
+}
+
+func TestPageCompact(t *testing.T) {
+	p, err := newPage("https://foo.com", []byte(`<a id="foo" href="bar">Hello!</a>`), newScraper(nil, defaultSchemes))
 	assert.Nil(t, err)
+	assert.False(t, p.Compacted())
 
-	assert.Equal(t, 1, len(p.IDs()))
+	q := p.Compact()
+
+	assert.True(t, q.Compacted())
+	assert.Equal(t, p.URL(), q.URL())
+	assert.Equal(t, p.IDs(), q.IDs())
+	assert.Nil(t, q.Links())
+	assert.Nil(t, q.Sources())
+	assert.Equal(t, 1, len(p.Links()))
 }
 
 func TestPageLinks(t *testing.T) {
@@ -74,10 +82,7 @@ func TestPageLinks(t *testing.T) {
 			"https://foo.com/foo/foo",
 		},
 	} {
-		n, err := html.Parse(strings.NewReader(ss[0]))
-		assert.Nil(t, err)
-
-		p, err := newPage("https://foo.com", n, newScraper(nil))
+		p, err := newPage("https://foo.com", []byte(ss[0]), newScraper(nil, defaultSchemes))
 		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(p.Links()))
@@ -85,5 +90,6 @@ func TestPageLinks(t *testing.T) {
 		_, ok := p.Links()[ss[1]]
 
 		assert.True(t, ok)
+		assert.Equal(t, 1, len(p.Sources()[ss[1]]))
 	}
 }
@@ -0,0 +1,166 @@
+package muffet
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxProgressHosts is a maximum number of hosts shown with in-flight requests.
+const maxProgressHosts = 3
+
+// clearLine is an escape sequence to clear a current line on terminals.
+const clearLine = "\r\x1b[K"
+
+// progressReporter writes progress of a check periodically. On terminals, it
+// rewrites a single line. Otherwise, it writes log lines.
+type progressReporter struct {
+	writer   io.Writer
+	terminal bool
+	interval time.Duration
+	snapshot func() statisticsSnapshot
+	mutex    *sync.Mutex
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func newProgressReporter(w io.Writer, t bool, i time.Duration, f func() statisticsSnapshot) progressReporter {
+	return progressReporter{w, t, i, f, &sync.Mutex{}, make(chan struct{}), make(chan struct{})}
+}
+
+// Start starts reporting progress in background.
+func (r progressReporter) Start() {
+	go func() {
+		defer close(r.stopped)
+
+		s := time.Now()
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				r.report(r.snapshot(), time.Since(s), false)
+			case <-r.done:
+				r.report(r.snapshot(), time.Since(s), true)
+				return
+			}
+		}
+	}()
+}
+
+// Stop writes final progress and stops reporting.
+func (r progressReporter) Stop() {
+	close(r.done)
+	<-r.stopped
+}
+
+// Writer returns a writer which clears a progress line before writes so that
+// outputs do not mix with it on terminals.
+func (r progressReporter) Writer(w io.Writer) io.Writer {
+	if !r.terminal {
+		return w
+	}
+
+	return progressClearingWriter{w, r}
+}
+
+type progressClearingWriter struct {
+	writer   io.Writer
+	reporter progressReporter
+}
+
+func (w progressClearingWriter) Write(bs []byte) (int, error) {
+	w.reporter.mutex.Lock()
+	defer w.reporter.mutex.Unlock()
+
+	fmt.Fprint(w.reporter.writer, clearLine)
+
+	return w.writer.Write(bs)
+}
+
+func (r progressReporter) report(s statisticsSnapshot, d time.Duration, last bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	l := formatProgress(s, d)
+
+	if !r.terminal {
+		fmt.Fprintln(r.writer, "progress:", l)
+		return
+	}
+
+	fmt.Fprint(r.writer, clearLine+l)
+
+	if last {
+		fmt.Fprintln(r.writer)
+	}
+}
+
+func formatProgress(s statisticsSnapshot, d time.Duration) string {
+	eta := "--"
+
+	if s.PagesChecked != 0 && s.PagesQueued() != 0 {
+		eta = formatDuration(d / time.Duration(s.PagesChecked) * time.Duration(s.PagesQueued()))
+	} else if s.PagesQueued() == 0 {
+		eta = formatDuration(0)
+	}
+
+	rps := 0.0
+
+	if d > 0 {
+		rps = float64(s.Requests) / d.Seconds()
+	}
+
+	return fmt.Sprintf(
+		"pages %v checked, %v queued | links %v | errors %v | %.1f req/s | in-flight %v | %v elapsed, ETA %v",
+		s.PagesChecked,
+		s.PagesQueued(),
+		s.LinksChecked,
+		s.Errors,
+		rps,
+		formatInFlightRequests(s.InFlightRequests),
+		formatDuration(d),
+		eta)
+}
+
+func formatInFlightRequests(m map[string]int) string {
+	if len(m) == 0 {
+		return "0"
+	}
+
+	hs := make([]string, 0, len(m))
+
+	for h := range m {
+		hs = append(hs, h)
+	}
+
+	sort.Slice(hs, func(i, j int) bool {
+		if m[hs[i]] != m[hs[j]] {
+			return m[hs[i]] > m[hs[j]]
+		}
+
+		return hs[i] < hs[j]
+	})
+
+	ss := []string{}
+
+	for i, h := range hs {
+		if i == maxProgressHosts {
+			ss = append(ss, fmt.Sprintf("+%v hosts", len(hs)-i))
+			break
+		}
+
+		ss = append(ss, fmt.Sprintf("%v=%v", h, m[h]))
+	}
+
+	return strings.Join(ss, " ")
+}
+
+func formatDuration(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
@@ -0,0 +1,92 @@
+package muffet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressReporter(t *testing.T) {
+	b := &bytes.Buffer{}
+	r := newProgressReporter(b, false, time.Millisecond, func() statisticsSnapshot {
+		return statisticsSnapshot{PagesFound: 2, PagesChecked: 1}
+	})
+
+	r.Start()
+	time.Sleep(10 * time.Millisecond)
+	r.Stop()
+
+	ls := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	assert.True(t, len(ls) > 1)
+
+	for _, l := range ls {
+		assert.True(t, strings.HasPrefix(l, "progress: pages 1 checked, 1 queued"))
+	}
+}
+
+func TestProgressReporterWithTerminal(t *testing.T) {
+	b := &bytes.Buffer{}
+	r := newProgressReporter(b, true, time.Hour, func() statisticsSnapshot { return statisticsSnapshot{} })
+
+	r.Start()
+	r.Stop()
+
+	assert.True(t, strings.HasPrefix(b.String(), clearLine+"pages 0 checked"))
+	assert.True(t, strings.HasSuffix(b.String(), "\n"))
+}
+
+func TestProgressReporterWriter(t *testing.T) {
+	b, c := &bytes.Buffer{}, &bytes.Buffer{}
+	r := newProgressReporter(b, true, time.Hour, func() statisticsSnapshot { return statisticsSnapshot{} })
+
+	_, err := r.Writer(c).Write([]byte("foo"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, clearLine, b.String())
+	assert.Equal(t, "foo", c.String())
+
+	r = newProgressReporter(b, false, time.Hour, func() statisticsSnapshot { return statisticsSnapshot{} })
+	assert.Equal(t, c, r.Writer(c))
+}
+
+func TestFormatProgress(t *testing.T) {
+	for _, c := range []struct {
+		statistics statisticsSnapshot
+		duration   time.Duration
+		answer     string
+	}{
+		{
+			statisticsSnapshot{},
+			0,
+			"pages 0 checked, 0 queued | links 0 | errors 0 | 0.0 req/s | in-flight 0 | 00:00:00 elapsed, ETA 00:00:00",
+		},
+		{
+			statisticsSnapshot{4, 1, 10, 2, 20, map[string]int{"foo.com": 2}},
+			10 * time.Second,
+			"pages 1 checked, 3 queued | links 10 | errors 2 | 2.0 req/s | in-flight foo.com=2 | 00:00:10 elapsed, ETA 00:00:30",
+		},
+		{
+			statisticsSnapshot{PagesFound: 1},
+			time.Second,
+			"pages 0 checked, 1 queued | links 0 | errors 0 | 0.0 req/s | in-flight 0 | 00:00:01 elapsed, ETA --",
+		},
+	} {
+		assert.Equal(t, c.answer, formatProgress(c.statistics, c.duration))
+	}
+}
+
+func TestFormatInFlightRequests(t *testing.T) {
+	assert.Equal(t, "0", formatInFlightRequests(nil))
+	assert.Equal(
+		t,
+		"c=3 a=1 b=1 +1 hosts",
+		formatInFlightRequests(map[string]int{"a": 1, "b": 1, "c": 3, "d": 1}))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "01:01:01", formatDuration(time.Hour+time.Minute+time.Second))
+}
@@ -0,0 +1,115 @@
+package muffet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+type redirect struct {
+	url        string
+	statusCode int
+	location   string
+}
+
+func newRedirect(u string, s int, l string) redirect {
+	return redirect{u, s, l}
+}
+
+func (r redirect) URL() string {
+	return r.url
+}
+
+func (r redirect) StatusCode() int {
+	return r.statusCode
+}
+
+func (r redirect) Location() string {
+	return r.location
+}
+
+// Permanent returns true if a link should be updated to point to a location.
+func (r redirect) Permanent() bool {
+	return r.statusCode == 301 || r.statusCode == 308
+}
+
+// Insecure returns true if a redirect downgrades HTTPS to HTTP.
+func (r redirect) Insecure() bool {
+	u, v, err := r.parse()
+	return err == nil && u.Scheme == "https" && v.Scheme == "http"
+}
+
+func (r redirect) CrossHost() bool {
+	u, v, err := r.parse()
+	return err == nil && u.Host != v.Host
+}
+
+func (r redirect) Warnings() []string {
+	ws := []string(nil)
+
+	if r.Permanent() {
+		ws = append(ws, fmt.Sprintf("permanent redirect (%v) to %v", r.statusCode, r.location))
+	}
+
+	if r.Insecure() {
+		ws = append(ws, fmt.Sprintf("insecure redirect to %v", r.location))
+	}
+
+	if r.CrossHost() {
+		ws = append(ws, fmt.Sprintf("cross-host redirect to %v", r.location))
+	}
+
+	return ws
+}
+
+func (r redirect) String() string {
+	return fmt.Sprintf("%v %v -> %v", r.statusCode, r.url, r.location)
+}
+
+type redirectJSON struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status"`
+	Location   string `json:"location"`
+}
+
+func (r redirect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redirectJSON{r.url, r.statusCode, r.location})
+}
+
+func (r *redirect) UnmarshalJSON(bs []byte) error {
+	j := redirectJSON{}
+
+	if err := json.Unmarshal(bs, &j); err != nil {
+		return err
+	}
+
+	*r = newRedirect(j.URL, j.StatusCode, j.Location)
+
+	return nil
+}
+
+func (r redirect) parse() (*url.URL, *url.URL, error) {
+	u, err := url.Parse(r.url)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, err := url.Parse(r.location)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, v, nil
+}
+
+func redirectWarnings(rs []redirect) []string {
+	ws := []string(nil)
+
+	for _, r := range rs {
+		ws = append(ws, r.Warnings()...)
+	}
+
+	return ws
+}
@@ -0,0 +1,69 @@
+package muffet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectPermanent(t *testing.T) {
+	for _, s := range []int{301, 308} {
+		assert.True(t, newRedirect("http://foo.com", s, "http://foo.com/bar").Permanent())
+	}
+
+	for _, s := range []int{300, 302, 303, 307} {
+		assert.False(t, newRedirect("http://foo.com", s, "http://foo.com/bar").Permanent())
+	}
+}
+
+func TestRedirectInsecure(t *testing.T) {
+	assert.True(t, newRedirect("https://foo.com", 302, "http://foo.com").Insecure())
+	assert.False(t, newRedirect("http://foo.com", 302, "https://foo.com").Insecure())
+	assert.False(t, newRedirect("https://foo.com", 302, "https://foo.com/bar").Insecure())
+}
+
+func TestRedirectCrossHost(t *testing.T) {
+	assert.True(t, newRedirect("https://foo.com", 302, "https://bar.com").CrossHost())
+	assert.True(t, newRedirect("https://foo.com", 302, "https://foo.com:8080").CrossHost())
+	assert.False(t, newRedirect("https://foo.com", 302, "https://foo.com/bar").CrossHost())
+	assert.False(t, newRedirect(":", 302, "https://bar.com").CrossHost())
+}
+
+func TestRedirectWarnings(t *testing.T) {
+	assert.Equal(t, []string(nil), newRedirect("https://foo.com", 302, "https://foo.com/bar").Warnings())
+	assert.Equal(
+		t,
+		[]string{
+			"permanent redirect (301) to http://bar.com",
+			"insecure redirect to http://bar.com",
+			"cross-host redirect to http://bar.com",
+		},
+		newRedirect("https://foo.com", 301, "http://bar.com").Warnings())
+}
+
+func TestRedirectString(t *testing.T) {
+	assert.Equal(t, "301 http://foo.com -> https://foo.com", newRedirect("http://foo.com", 301, "https://foo.com").String())
+}
+
+func TestRedirectMarshalJSON(t *testing.T) {
+	bs, err := json.Marshal(newRedirect("http://foo.com", 301, "https://foo.com"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"url":"http://foo.com","status":301,"location":"https://foo.com"}`, string(bs))
+}
+
+func TestRedirectUnmarshalJSON(t *testing.T) {
+	r := redirect{}
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"url":"http://foo.com","status":301,"location":"https://foo.com"}`), &r))
+	assert.Equal(t, newRedirect("http://foo.com", 301, "https://foo.com"), r)
+	assert.NotNil(t, json.Unmarshal([]byte(`{"url":0}`), &r))
+}
+
+func TestRedirectWarningsOfRedirects(t *testing.T) {
+	assert.Equal(t, 2, len(redirectWarnings([]redirect{
+		newRedirect("http://foo.com", 301, "http://foo.com/bar"),
+		newRedirect("http://foo.com/bar", 302, "http://bar.com"),
+	})))
+}
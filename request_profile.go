@@ -0,0 +1,33 @@
+package muffet
+
+import "net/textproto"
+
+const browserUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// requestProfiles are sets of headers sent with every request by name. The
+// "browser" profile mimics a browser for websites rejecting other clients.
+var requestProfiles = map[string]map[string]string{
+	"default": {
+		"Accept-Encoding": acceptedEncodings,
+	},
+	"browser": {
+		"User-Agent":      browserUserAgent,
+		"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"Accept-Language": "en-US,en;q=0.5",
+		"Accept-Encoding": acceptedEncodings,
+	},
+}
+
+// mergeHeaders merges headers into one. Headers later in arguments take
+// precedence regardless of cases of their names.
+func mergeHeaders(hss ...map[string]string) map[string]string {
+	m := map[string]string{}
+
+	for _, hs := range hss {
+		for k, v := range hs {
+			m[textproto.CanonicalMIMEHeaderKey(k)] = v
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,142 @@
+package muffet
+
+import (
+	"sort"
+)
+
+// Result is a result of checking links in a page.
+type Result struct {
+	result pageResult
+}
+
+// URL returns a URL of a page.
+func (r Result) URL() string {
+	return r.result.URL()
+}
+
+// OK returns true if a page has no erroneous links except ignored ones.
+func (r Result) OK() bool {
+	return r.result.OK()
+}
+
+// Links returns results of links in a page.
+func (r Result) Links() []LinkResult {
+	ls := make([]LinkResult, 0, len(r.result.Links()))
+
+	for _, l := range r.result.Links() {
+		ls = append(ls, LinkResult{l})
+	}
+
+	return ls
+}
+
+// ErrorKinds returns sorted kinds of errors which make a page erroneous.
+func (r Result) ErrorKinds() []ErrorKind {
+	ks := make([]ErrorKind, 0, len(r.result.ErrorKinds()))
+
+	for k := range r.result.ErrorKinds() {
+		ks = append(ks, k)
+	}
+
+	sort.Slice(ks, func(i, j int) bool { return ks[i] < ks[j] })
+
+	return ks
+}
+
+// String returns a colored text report of a page. Successful links are
+// included only if verbose is true.
+func (r Result) String(verbose bool) string {
+	return r.result.String(verbose)
+}
+
+// MarshalJSON marshals a result into JSON.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return r.result.MarshalJSON()
+}
+
+// LinkResult is a result of checking a link.
+type LinkResult struct {
+	result linkResult
+}
+
+// URL returns a URL of a link.
+func (r LinkResult) URL() string {
+	return r.result.URL()
+}
+
+// StatusCode returns a status code of a response. It is zero if no response
+// is received.
+func (r LinkResult) StatusCode() int {
+	return r.result.StatusCode()
+}
+
+// Error returns an error on a link or nil.
+func (r LinkResult) Error() error {
+	return r.result.Error()
+}
+
+// ErrorKind returns a kind of an error on a link or an empty string.
+func (r LinkResult) ErrorKind() ErrorKind {
+	return r.result.ErrorKind()
+}
+
+// OK returns true if a link has no error.
+func (r LinkResult) OK() bool {
+	return r.result.OK()
+}
+
+// Ignored returns true if an error on a link does not make its page erroneous.
+func (r LinkResult) Ignored() bool {
+	return r.result.Ignored()
+}
+
+// Warnings returns warnings on a link.
+func (r LinkResult) Warnings() []string {
+	return r.result.Warnings()
+}
+
+// Redirects returns redirects followed on a request for a link.
+func (r LinkResult) Redirects() []Redirect {
+	rs := make([]Redirect, 0, len(r.result.Redirects()))
+
+	for _, x := range r.result.Redirects() {
+		rs = append(rs, Redirect{x.URL(), x.StatusCode(), x.Location()})
+	}
+
+	return rs
+}
+
+// Sources returns locations of a link in a source of its page.
+func (r LinkResult) Sources() []LinkSource {
+	ss := make([]LinkSource, 0, len(r.result.Sources()))
+
+	for _, x := range r.result.Sources() {
+		ss = append(ss, LinkSource{x.Tag(), x.Attribute(), x.Value(), x.Line(), x.Column(), x.Text(), x.HeadingID()})
+	}
+
+	return ss
+}
+
+// MarshalJSON marshals a result into JSON.
+func (r LinkResult) MarshalJSON() ([]byte, error) {
+	return r.result.MarshalJSON()
+}
+
+// Redirect is a redirect from a URL to a location.
+type Redirect struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+// LinkSource is a location of a link in an HTML source. Line and column
+// numbers start from 1 and point to the beginning of a tag.
+type LinkSource struct {
+	Tag       string
+	Attribute string
+	Value     string
+	Line      int
+	Column    int
+	Text      string
+	HeadingID string
+}
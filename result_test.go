@@ -0,0 +1,52 @@
+package muffet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult(t *testing.T) {
+	r := Result{newPageResult("foo", []linkResult{
+		newLinkResult("bar", 200, nil),
+		newLinkResult("baz", 0, newHTTPStatusError(404)),
+		newLinkResult("qux", 0, newFetchError(ErrorKindDNS, errors.New("dns"))),
+	})}
+
+	assert.Equal(t, "foo", r.URL())
+	assert.False(t, r.OK())
+	assert.Equal(t, 3, len(r.Links()))
+	assert.Equal(t, []ErrorKind{ErrorKindDNS, ErrorKindHTTPStatus}, r.ErrorKinds())
+	assert.Equal(t, r.result.String(true), r.String(true))
+
+	bs, err := r.MarshalJSON()
+	assert.Nil(t, err)
+
+	cs, err := r.result.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, cs, bs)
+}
+
+func TestLinkResult(t *testing.T) {
+	r := LinkResult{
+		newLinkResult("foo", 0, newHTTPStatusError(404)).
+			Ignore().
+			WithRedirects([]redirect{newRedirect("foo", 301, "bar")}).
+			Warn("baz").
+			WithSources([]linkSource{newLinkSource("a", "href", "foo", 1, 2, "qux", "quux")}),
+	}
+
+	assert.Equal(t, "foo", r.URL())
+	assert.Equal(t, 404, r.StatusCode())
+	assert.NotNil(t, r.Error())
+	assert.Equal(t, ErrorKindHTTPStatus, r.ErrorKind())
+	assert.False(t, r.OK())
+	assert.True(t, r.Ignored())
+	assert.Equal(t, []string{"baz"}, r.Warnings())
+	assert.Equal(t, []Redirect{{"foo", 301, "bar"}}, r.Redirects())
+	assert.Equal(t, []LinkSource{{"a", "href", "foo", 1, 2, "qux", "quux"}}, r.Sources())
+
+	_, err := r.MarshalJSON()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,63 @@
+package muffet
+
+import (
+	"net/url"
+
+	"github.com/temoto/robotstxt"
+)
+
+// robotsTxtCache fetches robots.txt of hosts lazily and caches them by host.
+type robotsTxtCache struct {
+	client    sitemapClient
+	userAgent string
+	cache     cache
+}
+
+func newRobotsTxtCache(c sitemapClient, a string) robotsTxtCache {
+	return robotsTxtCache{c, a, newCache()}
+}
+
+// Allowed returns true if a URL is allowed by robots.txt of its host.
+func (c robotsTxtCache) Allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return true
+	}
+
+	r := c.robotsData(u)
+
+	return r == nil || r.TestAgent(u.EscapedPath(), c.userAgent)
+}
+
+func (c robotsTxtCache) robotsData(u *url.URL) *robotstxt.RobotsData {
+	k := u.Scheme + "://" + u.Host
+	x, store, ok := c.cache.LoadOrStore(k)
+
+	if ok {
+		return x.(*robotstxt.RobotsData)
+	}
+
+	r := fetchRobotsData(c.client, k)
+	store(r)
+
+	return r
+}
+
+// fetchRobotsData fetches robots.txt at a base URL. It returns nil to allow
+// every URL if robots.txt is invalid or not fetched. Missing robots.txt allows
+// every URL and server errors disallow them as per the robots exclusion
+// protocol.
+func fetchRobotsData(c sitemapClient, s string) *robotstxt.RobotsData {
+	n, bs, err := c.Get(s + "/robots.txt")
+
+	if err != nil {
+		return nil
+	}
+
+	r, err := robotstxt.FromStatusAndBytes(n, bs)
+
+	if err != nil {
+		return nil
+	}
+
+	return r
+}
@@ -0,0 +1,65 @@
+package muffet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRobotsTxtCacheAllowed(t *testing.T) {
+	c := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), defaultRobotsTxtUserAgent)
+
+	for _, s := range []string{rootURL, existentURL, missingMetadataURL + "/foo", noResponseURL + "/foo"} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.True(t, c.Allowed(u))
+	}
+
+	for _, s := range []string{erroneousURL, fragmentURL} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.False(t, c.Allowed(u))
+	}
+}
+
+func TestRobotsTxtCacheAllowedWithUserAgent(t *testing.T) {
+	c := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), "strict")
+
+	u, err := url.Parse(existentURL)
+	assert.Nil(t, err)
+	assert.False(t, c.Allowed(u))
+
+	u, err = url.Parse(missingMetadataURL + "/foo")
+	assert.Nil(t, err)
+	assert.True(t, c.Allowed(u))
+}
+
+func TestRobotsTxtCacheAllowedWithInvalidRobotsTxt(t *testing.T) {
+	c := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), defaultRobotsTxtUserAgent)
+
+	u, err := url.Parse(invalidRobotsTxtURL + "/foo")
+	assert.Nil(t, err)
+	assert.True(t, c.Allowed(u))
+}
+
+func TestRobotsTxtCacheAllowedWithRedirectedRobotsTxt(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.Redirect(w, r, "/foo/robots.txt", http.StatusMovedPermanently)
+		case "/foo/robots.txt":
+			w.Write([]byte("User-agent: " + r.Header.Get("User-Agent") + "\nDisallow: /bar\n"))
+		}
+	}))
+	defer s.Close()
+
+	c := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{UserAgent: "foo"}), "foo")
+
+	u, err := url.Parse(s.URL + "/bar")
+	assert.Nil(t, err)
+	assert.False(t, c.Allowed(u))
+}
@@ -0,0 +1,228 @@
+package muffet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// schemes are schemes of links which can be checked.
+var schemes = map[string]struct{}{
+	"http":   {},
+	"https":  {},
+	"mailto": {},
+	"tel":    {},
+	"ftp":    {},
+	"file":   {},
+}
+
+// ignoredSchemes are known schemes of links never checked.
+var ignoredSchemes = map[string]struct{}{
+	"about":      {},
+	"blob":       {},
+	"data":       {},
+	"javascript": {},
+}
+
+var telephoneNumberPattern = regexp.MustCompile(`^\+?[0-9().\-]*[0-9][0-9().\-]*$`)
+
+// schemeValidator validates URLs of a scheme not fetched over HTTP.
+type schemeValidator interface {
+	Validate(u *url.URL) error
+}
+
+// newSchemeValidators creates validators of enabled schemes other than HTTP.
+func newSchemeValidators(o fetcherOptions, d fasthttp.DialFunc) map[string]schemeValidator {
+	r := newResolver(o.DNSServer)
+	m := map[string]schemeValidator{}
+
+	for _, s := range o.Schemes {
+		switch s {
+		case "mailto":
+			m[s] = mailtoValidator{r, o.CheckMX, o.Timeout}
+		case "tel":
+			m[s] = telValidator{}
+		case "ftp":
+			m[s] = ftpValidator{d, o.Timeout}
+		case "file":
+			m[s] = fileValidator{}
+		}
+	}
+
+	return m
+}
+
+// isKnownScheme returns true if links of a scheme can be checked or are
+// ignored deliberately.
+func isKnownScheme(s string) bool {
+	if _, ok := schemes[s]; ok {
+		return true
+	}
+
+	_, ok := ignoredSchemes[s]
+	return ok
+}
+
+// urlScheme returns a scheme of a URL or an empty string if it is invalid.
+func urlScheme(s string) string {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme
+}
+
+// mailtoValidator validates syntax of addresses in mailto URLs and optionally
+// their domains' MX records.
+type mailtoValidator struct {
+	resolver *net.Resolver
+	checkMX  bool
+	timeout  time.Duration
+}
+
+func (v mailtoValidator) Validate(u *url.URL) error {
+	ss := []string(nil)
+
+	if u.Opaque != "" {
+		s, err := url.PathUnescape(u.Opaque)
+
+		if err != nil {
+			return newFetchError(ErrorKindParse, err)
+		}
+
+		ss = append(ss, s)
+	}
+
+	for _, s := range u.Query()["to"] {
+		if s != "" {
+			ss = append(ss, s)
+		}
+	}
+
+	// Links without addresses are valid as per RFC 6068 and often used to
+	// let users choose recipients of shared pages.
+	if len(ss) == 0 {
+		return nil
+	}
+
+	as, err := mail.ParseAddressList(strings.Join(ss, ","))
+
+	if err != nil {
+		return newFetchError(ErrorKindParse, err)
+	} else if !v.checkMX {
+		return nil
+	}
+
+	for _, a := range as {
+		if err := v.lookupMX(a.Address[strings.LastIndex(a.Address, "@")+1:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookupMX checks if a domain accepts emails. Domains without MX records
+// accept them at their addresses as per RFC 5321.
+func (v mailtoValidator) lookupMX(d string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+
+	if ms, err := v.resolver.LookupMX(ctx, d); err == nil && len(ms) != 0 {
+		return nil
+	}
+
+	if _, err := v.resolver.LookupHost(ctx, d); err != nil {
+		return newFetchError(ErrorKindDNS, err)
+	}
+
+	return nil
+}
+
+// telValidator validates syntax of telephone numbers in tel URLs.
+type telValidator struct{}
+
+func (telValidator) Validate(u *url.URL) error {
+	s, err := url.PathUnescape(strings.SplitN(u.Opaque, ";", 2)[0])
+
+	if err != nil {
+		return newFetchError(ErrorKindParse, err)
+	} else if !telephoneNumberPattern.MatchString(strings.Replace(s, " ", "", -1)) {
+		return newFetchError(ErrorKindParse, fmt.Errorf("invalid telephone number: %v", s))
+	}
+
+	return nil
+}
+
+// ftpValidator checks if FTP servers accept connections. Servers are dialed
+// in the same way as HTTP servers.
+type ftpValidator struct {
+	dial    fasthttp.DialFunc
+	timeout time.Duration
+}
+
+func (v ftpValidator) Validate(u *url.URL) error {
+	h := u.Host
+
+	if u.Port() == "" {
+		h = net.JoinHostPort(u.Hostname(), "21")
+	}
+
+	c, err := v.dial(h)
+
+	if err != nil {
+		return wrapError(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SetDeadline(time.Now().Add(v.timeout)); err != nil {
+		return wrapError(err)
+	}
+
+	if _, _, err := textproto.NewConn(c).ReadResponse(2); err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// fileValidator checks if files at file URLs exist, which is useful only on
+// checking local files.
+type fileValidator struct{}
+
+func (fileValidator) Validate(u *url.URL) error {
+	if _, err := os.Stat(u.Path); err != nil {
+		return newFetchError(ErrorKindOther, err)
+	}
+
+	return nil
+}
+
+// newResolver creates a DNS resolver using a server at an address or the
+// system one if the address is empty.
+func newResolver(s string) *net.Resolver {
+	if s == "" {
+		return net.DefaultResolver
+	} else if _, _, err := net.SplitHostPort(s); err != nil {
+		s = net.JoinHostPort(s, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, n, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, n, s)
+		},
+	}
+}
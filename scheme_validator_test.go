@@ -0,0 +1,165 @@
+package muffet
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewSchemeValidators(t *testing.T) {
+	vs := newSchemeValidators(fetcherOptions{Schemes: []string{"http", "mailto", "tel", "ftp", "file"}}, nil)
+
+	assert.Equal(t, 4, len(vs))
+
+	for _, s := range []string{"mailto", "tel", "ftp", "file"} {
+		_, ok := vs[s]
+		assert.True(t, ok)
+	}
+}
+
+func TestIsKnownScheme(t *testing.T) {
+	for _, s := range []string{"http", "https", "mailto", "tel", "ftp", "file", "javascript", "data"} {
+		assert.True(t, isKnownScheme(s))
+	}
+
+	for _, s := range []string{"gopher", "foo"} {
+		assert.False(t, isKnownScheme(s))
+	}
+}
+
+func TestURLScheme(t *testing.T) {
+	assert.Equal(t, "https", urlScheme("https://foo.com"))
+	assert.Equal(t, "mailto", urlScheme("mailto:me@foo.com"))
+	assert.Equal(t, "", urlScheme(":"))
+}
+
+func TestMailtoValidatorValidate(t *testing.T) {
+	v := mailtoValidator{net.DefaultResolver, false, time.Second}
+
+	for _, s := range []string{
+		"mailto:me@foo.com",
+		"mailto:me@foo.com,you@bar.com",
+		"mailto:Me%20%3Cme@foo.com%3E",
+		"mailto:me@foo.com?subject=hello",
+		"mailto:?to=me@foo.com",
+		"mailto:",
+		"mailto:?subject=hello&body=https%3A%2F%2Ffoo.com",
+		"mailto:?to=&subject=hello",
+	} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.Nil(t, v.Validate(u))
+	}
+}
+
+func TestMailtoValidatorValidateError(t *testing.T) {
+	v := mailtoValidator{net.DefaultResolver, false, time.Second}
+
+	for _, s := range []string{
+		"mailto:me",
+		"mailto:me@",
+		"mailto:?to=me",
+	} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.Equal(t, ErrorKindParse, errorKindOf(v.Validate(u)))
+	}
+}
+
+func TestMailtoValidatorValidateWithMX(t *testing.T) {
+	u, err := url.Parse("mailto:me@foo.invalid")
+	assert.Nil(t, err)
+
+	err = mailtoValidator{net.DefaultResolver, true, time.Second}.Validate(u)
+
+	assert.Equal(t, ErrorKindDNS, errorKindOf(err))
+}
+
+func TestTelValidatorValidate(t *testing.T) {
+	for _, s := range []string{
+		"tel:+1-234-567-8901",
+		"tel:+81%2090%201234%205678",
+		"tel:(03)1234-5678",
+		"tel:911",
+		"tel:+1-234-567-8901;ext=123",
+	} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.Nil(t, telValidator{}.Validate(u))
+	}
+}
+
+func TestTelValidatorValidateError(t *testing.T) {
+	for _, s := range []string{"tel:", "tel:foo", "tel:+1-234-FOO", "tel:---"} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+		assert.Equal(t, ErrorKindParse, errorKindOf(telValidator{}.Validate(u)))
+	}
+}
+
+func TestFTPValidatorValidate(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	go func() {
+		c, err := l.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer c.Close()
+
+		c.Write([]byte("220 Welcome\r\n"))
+	}()
+
+	_, p, err := net.SplitHostPort(l.Addr().String())
+	assert.Nil(t, err)
+
+	u, err := url.Parse("ftp://foo.invalid:" + p + "/foo")
+	assert.Nil(t, err)
+
+	d := newDialFunc(fetcherOptions{
+		Resolves: map[string]string{"foo.invalid:" + p: "127.0.0.1"},
+		DialMode: "dual",
+		Timeout:  time.Second,
+	})
+
+	assert.Nil(t, ftpValidator{d, time.Second}.Validate(u))
+}
+
+func TestFTPValidatorValidateError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	a := l.Addr().String()
+	assert.Nil(t, l.Close())
+
+	u, err := url.Parse("ftp://" + a + "/foo")
+	assert.Nil(t, err)
+	assert.NotNil(t, ftpValidator{fasthttp.Dial, time.Second}.Validate(u))
+}
+
+func TestFileValidatorValidate(t *testing.T) {
+	d, err := os.Getwd()
+	assert.Nil(t, err)
+
+	u := &url.URL{Scheme: "file", Path: d + "/scheme_validator.go"}
+
+	assert.Nil(t, fileValidator{}.Validate(u))
+
+	u.Path = d + "/foo"
+
+	assert.Equal(t, ErrorKindOther, errorKindOf(fileValidator{}.Validate(u)))
+}
+
+func TestNewResolver(t *testing.T) {
+	assert.Equal(t, net.DefaultResolver, newResolver(""))
+	assert.True(t, newResolver("127.0.0.1").PreferGo)
+}
@@ -1,22 +1,15 @@
 package muffet
 
 import (
+	"errors"
 	"net/url"
 	"regexp"
 	"strings"
 	"unicode"
 
-	"github.com/yhat/scrape"
-	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
-var validSchemes = map[string]struct{}{
-	"":      {},
-	"http":  {},
-	"https": {},
-}
-
 var atomToAttributes = map[atom.Atom][]string{
 	atom.A:      {"href"},
 	atom.Frame:  {"src"},
@@ -30,44 +23,67 @@ var atomToAttributes = map[atom.Atom][]string{
 
 type scraper struct {
 	excludedPatterns []*regexp.Regexp
+	schemes          map[string]struct{}
 }
 
-func newScraper(rs []*regexp.Regexp) scraper {
-	return scraper{rs}
-}
+// newScraper creates a scraper of links with enabled schemes. Links of
+// unknown schemes are also scraped so that they are reported.
+func newScraper(rs []*regexp.Regexp, ss []string) scraper {
+	m := make(map[string]struct{}, len(ss)+1)
+	m[""] = struct{}{}
 
-func (sc scraper) Scrape(n *html.Node, base *url.URL) map[string]error {
-	us := map[string]error{}
+	for _, s := range ss {
+		m[s] = struct{}{}
+	}
 
-	for _, n := range scrape.FindAllNested(n, func(n *html.Node) bool {
-		_, ok := atomToAttributes[n.DataAtom]
-		return ok
-	}) {
-		for _, a := range atomToAttributes[n.DataAtom] {
-			s := normalizeURL(scrape.Attr(n, a))
+	return scraper{rs, m}
+}
 
-			if s == "" || sc.isURLExcluded(s) {
-				continue
-			}
+// Scrape resolves URLs of links at their sources with a base URL.
+func (sc scraper) Scrape(ls []linkSource, base *url.URL) map[string]error {
+	us := map[string]error{}
 
-			u, err := url.Parse(s)
+	for _, l := range ls {
+		s := normalizeURL(l.Value())
+		x := sc.isURLExcluded(s)
+		s, err := sc.resolveLinkURL(s, base)
 
-			if err != nil {
+		if s == "" {
+			continue
+		} else if err != nil {
+			if !x {
 				us[s] = err
-				continue
 			}
 
-			if _, ok := validSchemes[u.Scheme]; !ok {
-				continue
+			continue
+		} else if x {
+			if _, ok := us[s]; !ok {
+				us[s] = newFetchError(ErrorKindExcluded, errors.New("excluded"))
 			}
 
-			us[base.ResolveReference(u).String()] = nil
+			continue
 		}
+
+		us[s] = nil
 	}
 
 	return us
 }
 
+// Sources groups sources of links by their URLs resolved in the same way as
+// links scraped from a page.
+func (sc scraper) Sources(ls []linkSource, base *url.URL) map[string][]linkSource {
+	m := map[string][]linkSource{}
+
+	for _, l := range ls {
+		if s, _ := sc.resolveLinkURL(normalizeURL(l.Value()), base); s != "" {
+			m[s] = append(m[s], l)
+		}
+	}
+
+	return m
+}
+
 func (sc scraper) isURLExcluded(u string) bool {
 	for _, r := range sc.excludedPatterns {
 		if r.MatchString(u) {
@@ -78,6 +94,25 @@ func (sc scraper) isURLExcluded(u string) bool {
 	return false
 }
 
+// resolveLinkURL resolves a normalized URL of a link with a base URL. It
+// returns an empty string if the link is not checked, or the URL as it is
+// with an error if it is invalid.
+func (sc scraper) resolveLinkURL(s string, base *url.URL) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return s, err
+	} else if _, ok := sc.schemes[u.Scheme]; !ok && isKnownScheme(u.Scheme) {
+		return "", nil
+	}
+
+	return base.ResolveReference(u).String(), nil
+}
+
 func normalizeURL(s string) string {
 	return strings.Map(func(r rune) rune {
 		if unicode.IsSpace(r) {
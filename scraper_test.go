@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/net/html"
 )
 
 func TestScrapePage(t *testing.T) {
@@ -33,12 +32,9 @@ func TestScrapePage(t *testing.T) {
 		{`<a href="/"><img src="/foo.png" /></a>`, 2},
 		{`<a href="/" /><a href="/" />`, 1},
 	} {
-		n, err := html.Parse(strings.NewReader(htmlWithBody(c.html)))
-		assert.Nil(t, err)
-
 		s, e := 0, 0
 
-		for _, err := range newScraper(nil).Scrape(n, b) {
+		for _, err := range newScraper(nil, defaultSchemes).Scrape(scanHTML(strings.NewReader(htmlWithBody(c.html))).sources, b) {
 			if err == nil {
 				s++
 			} else {
@@ -55,12 +51,9 @@ func TestScrapePageError(t *testing.T) {
 	b, err := url.Parse("https://localhost")
 	assert.Nil(t, err)
 
-	n, err := html.Parse(strings.NewReader(htmlWithBody(`<a href=":" />`)))
-	assert.Nil(t, err)
-
 	s, e := 0, 0
 
-	for _, err := range newScraper(nil).Scrape(n, b) {
+	for _, err := range newScraper(nil, defaultSchemes).Scrape(scanHTML(strings.NewReader(htmlWithBody(`<a href=":" />`))).sources, b) {
 		if err == nil {
 			s++
 		} else {
@@ -72,6 +65,65 @@ func TestScrapePageError(t *testing.T) {
 	assert.Equal(t, 1, e)
 }
 
+func TestScrapePageWithExcludedURLs(t *testing.T) {
+	b, err := url.Parse("https://localhost")
+	assert.Nil(t, err)
+
+	rs, err := compileRegexps([]string{"foo"})
+	assert.Nil(t, err)
+
+	us := newScraper(rs, defaultSchemes).Scrape(scanHTML(strings.NewReader(htmlWithBody(`<a href="/foo" /><a href="/bar" />`))).sources, b)
+
+	assert.Nil(t, us["https://localhost/bar"])
+	assert.Equal(t, ErrorKindExcluded, errorKindOf(us["https://localhost/foo"]))
+}
+
+func TestScrapePageWithSchemes(t *testing.T) {
+	b, err := url.Parse("https://localhost")
+	assert.Nil(t, err)
+
+	for _, c := range []struct {
+		schemes []string
+		links   int
+	}{
+		{[]string{"http", "https"}, 2},
+		{[]string{"http", "https", "mailto"}, 3},
+		{[]string{"http", "https", "mailto", "tel"}, 4},
+	} {
+		us := newScraper(nil, c.schemes).Scrape(
+			scanHTML(strings.NewReader(htmlWithBody(`
+				<a href="/foo" />
+				<a href="mailto:me@right.here" />
+				<a href="tel:+1-234-567-8901" />
+				<a href="javascript:void(0)" />
+				<a href="gopher://localhost" />
+			`))).sources,
+			b,
+		)
+
+		assert.Equal(t, c.links, len(us))
+		assert.Contains(t, us, "gopher://localhost")
+		assert.NotContains(t, us, "javascript:void(0)")
+	}
+}
+
+func TestScraperSources(t *testing.T) {
+	b, err := url.Parse("https://localhost/foo/")
+	assert.Nil(t, err)
+
+	ss := newScraper(nil, []string{"http", "https"}).Sources([]linkSource{
+		newLinkSource("a", "href", "bar", 1, 1, "", ""),
+		newLinkSource("a", "href", " /foo/bar ", 2, 1, "", ""),
+		newLinkSource("a", "href", ":", 3, 1, "", ""),
+		newLinkSource("a", "href", "mailto:me@right.here", 4, 1, "", ""),
+		newLinkSource("a", "href", "", 5, 1, "", ""),
+	}, b)
+
+	assert.Equal(t, 2, len(ss))
+	assert.Equal(t, 2, len(ss["https://localhost/foo/bar"]))
+	assert.Equal(t, 3, ss[":"][0].Line())
+}
+
 func TestScraperIsURLExcluded(t *testing.T) {
 	for _, x := range []struct {
 		url     string
@@ -102,6 +154,6 @@ func TestScraperIsURLExcluded(t *testing.T) {
 		rs, err := compileRegexps(x.regexps)
 		assert.Nil(t, err)
 
-		assert.Equal(t, x.answer, newScraper(rs).isURLExcluded(x.url))
+		assert.Equal(t, x.answer, newScraper(rs, defaultSchemes).isURLExcluded(x.url))
 	}
 }
@@ -0,0 +1,284 @@
+package muffet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// server runs checks of websites as jobs requested over an HTTP API.
+type server struct {
+	mutex  *sync.Mutex
+	jobs   map[string]*job
+	caches map[string]*jobCache
+	nextID *int
+	// jobTTL is a duration for which finished jobs are kept.
+	jobTTL time.Duration
+}
+
+// jobCache is a cache shared among running jobs with the same options.
+type jobCache struct {
+	cache
+	jobs int
+}
+
+func newServer() server {
+	return server{&sync.Mutex{}, map[string]*job{}, map[string]*jobCache{}, new(int), finishedJobTTL}
+}
+
+type jobRequest struct {
+	URL     string                 `json:"url"`
+	Options map[string]interface{} `json:"options"`
+}
+
+func (s server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ps := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if ps[0] != "jobs" || len(ps) > 3 {
+		http.NotFound(w, r)
+		return
+	} else if len(ps) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.listJobs(w)
+		case http.MethodPost:
+			s.createJob(w, r)
+		default:
+			methodNotAllowed(w)
+		}
+
+		return
+	}
+
+	j, ok := s.job(ps[1])
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch p := strings.Join(ps[2:], "/"); {
+	case p == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, j)
+	case p == "" && r.Method == http.MethodDelete:
+		j.Cancel()
+		writeJSON(w, http.StatusAccepted, j)
+	case p == "events" && r.Method == http.MethodGet:
+		streamJobEvents(w, r, j)
+	case p == "report" && r.Method == http.MethodGet:
+		writeJobReport(w, j)
+	case p == "" || p == "events" || p == "report":
+		methodNotAllowed(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s server) createJob(w http.ResponseWriter, r *http.Request) {
+	req := jobRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url not specified")
+		return
+	}
+
+	ss, err := jobOptionsToArguments(req.URL, req.Options)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	args, err := getJobArguments(ss)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(nil)
+
+	if args.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, args.MaxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	k := strings.Join(ss[:len(ss)-1], "\x00")
+	ca := s.acquireCache(k)
+
+	s.mutex.Lock()
+	*s.nextID++
+	j := newJob(strconv.Itoa(*s.nextID), args.URL, args.WarnedErrorKinds, cancel)
+	s.jobs[j.ID()] = j
+	s.mutex.Unlock()
+
+	go func() {
+		defer cancel()
+
+		j.Run(ctx, func() (checker, error) {
+			return newCheckerWithCache(args.URL, newCheckerOptions(args), ca)
+		})
+
+		s.releaseCache(k)
+		time.AfterFunc(s.jobTTL, func() { s.deleteJob(j.ID()) })
+	}()
+
+	w.Header().Set("Location", "/jobs/"+j.ID())
+	writeJSON(w, http.StatusCreated, j)
+}
+
+func (s server) listJobs(w http.ResponseWriter) {
+	s.mutex.Lock()
+	js := make([]*job, 0, len(s.jobs))
+
+	for _, j := range s.jobs {
+		js = append(js, j)
+	}
+
+	s.mutex.Unlock()
+
+	sort.Slice(js, func(i, k int) bool {
+		m, _ := strconv.Atoi(js[i].ID())
+		n, _ := strconv.Atoi(js[k].ID())
+		return m < n
+	})
+
+	writeJSON(w, http.StatusOK, js)
+}
+
+func (s server) job(id string) (*job, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+func (s server) deleteJob(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.jobs, id)
+}
+
+// acquireCache returns a cache shared among running jobs with the same
+// options. Jobs started after all of them finish never see stale results.
+func (s server) acquireCache(k string) cache {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.caches[k]
+
+	if !ok {
+		c = &jobCache{newCache(), 0}
+		s.caches[k] = c
+	}
+
+	c.jobs++
+	return c.cache
+}
+
+// releaseCache releases a cache acquired by a finished job.
+func (s server) releaseCache(k string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c := s.caches[k]
+	c.jobs--
+
+	if c.jobs == 0 {
+		delete(s.caches, k)
+	}
+}
+
+func writeJobReport(w http.ResponseWriter, j *job) {
+	if j.Status() == jobStatusRunning {
+		writeError(w, http.StatusConflict, "job is still running")
+		return
+	}
+
+	bs, err := j.MarshalReportJSON()
+
+	if err != nil {
+		panic(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(bs, '\n'))
+}
+
+// streamJobEvents streams page results of a job as Server-Sent Events.
+func streamJobEvents(w http.ResponseWriter, r *http.Request, j *job) {
+	f, ok := w.(http.Flusher)
+
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for i := 0; ; {
+		rs, done, c := j.Results(i)
+
+		for _, r := range rs {
+			writeEvent(w, "page", r)
+		}
+
+		i += len(rs)
+
+		if done {
+			writeEvent(w, "done", j)
+			f.Flush()
+			return
+		}
+
+		f.Flush()
+
+		select {
+		case <-c:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e string, x interface{}) {
+	bs, err := json.Marshal(x)
+
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(w, "event: %v\ndata: %s\n\n", e, bs)
+}
+
+func writeJSON(w http.ResponseWriter, s int, x interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(s)
+
+	// Errors on writes are ignored as clients might have gone.
+	json.NewEncoder(w).Encode(x)
+}
+
+func writeError(w http.ResponseWriter, s int, e string) {
+	writeJSON(w, s, struct {
+		Error string `json:"error"`
+	}{e})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
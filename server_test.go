@@ -0,0 +1,179 @@
+package muffet
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerCreateJob(t *testing.T) {
+	s := httptest.NewServer(newServer())
+	defer s.Close()
+
+	r := createTestJob(t, s, `{"url":"`+rootURL+`","options":{"concurrency":1}}`)
+	defer r.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, r.StatusCode)
+	assert.Equal(t, "/jobs/1", r.Header.Get("Location"))
+}
+
+func TestServerCreateJobError(t *testing.T) {
+	s := httptest.NewServer(newServer())
+	defer s.Close()
+
+	for _, b := range []string{
+		"",
+		`{}`,
+		`{"url":"` + rootURL + `","options":{"concurrency":"foo"}}`,
+		`{"url":"` + rootURL + `","options":{"foo":true}}`,
+		`{"url":"` + rootURL + `","options":{"concurrency":null}}`,
+	} {
+		r := createTestJob(t, s, b)
+		r.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	}
+}
+
+func TestServerStreamJobEvents(t *testing.T) {
+	s := httptest.NewServer(newServer())
+	defer s.Close()
+
+	createTestJob(t, s, `{"url":"`+rootURL+`"}`).Body.Close()
+
+	r, err := http.Get(s.URL + "/jobs/1/events")
+	assert.Nil(t, err)
+	defer r.Body.Close()
+
+	assert.Equal(t, "text/event-stream", r.Header.Get("Content-Type"))
+
+	es := []string{}
+	sc := bufio.NewScanner(r.Body)
+
+	for sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "event: ") {
+			es = append(es, strings.TrimPrefix(sc.Text(), "event: "))
+		}
+	}
+
+	assert.Equal(t, "done", es[len(es)-1])
+
+	for _, e := range es[:len(es)-1] {
+		assert.Equal(t, "page", e)
+	}
+
+	r, err = http.Get(s.URL + "/jobs/1/report")
+	assert.Nil(t, err)
+	defer r.Body.Close()
+
+	j := struct {
+		Status string
+		OK     bool
+		Pages  []struct{ URL string }
+	}{}
+
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Nil(t, json.NewDecoder(r.Body).Decode(&j))
+	assert.Equal(t, "succeeded", j.Status)
+	assert.True(t, j.OK)
+	assert.Equal(t, len(es)-1, len(j.Pages))
+}
+
+func TestServerGetJob(t *testing.T) {
+	s := newServer()
+	s.jobs["1"] = newJob("1", rootURL, nil, func() {})
+
+	for p, c := range map[string]int{
+		"/jobs":          http.StatusOK,
+		"/jobs/1":        http.StatusOK,
+		"/jobs/1/report": http.StatusConflict,
+		"/jobs/2":        http.StatusNotFound,
+		"/jobs/1/foo":    http.StatusNotFound,
+		"/foo":           http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+
+		assert.Equal(t, c, w.Code)
+	}
+}
+
+func TestServerCancelJob(t *testing.T) {
+	s := newServer()
+	canceled := false
+	s.jobs["1"] = newJob("1", rootURL, nil, func() { canceled = true })
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/jobs/1", nil))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.True(t, canceled)
+}
+
+func TestServerWithInvalidMethods(t *testing.T) {
+	s := newServer()
+	s.jobs["1"] = newJob("1", rootURL, nil, func() {})
+
+	for _, p := range []string{"/jobs", "/jobs/1", "/jobs/1/events", "/jobs/1/report"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPut, p, nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestServerCache(t *testing.T) {
+	s := newServer()
+	c := s.acquireCache("--concurrency=1")
+
+	assert.True(t, c.values == s.acquireCache("--concurrency=1").values)
+	assert.False(t, c.values == s.acquireCache("--concurrency=2").values)
+
+	s.releaseCache("--concurrency=1")
+	assert.True(t, c.values == s.acquireCache("--concurrency=1").values)
+
+	s.releaseCache("--concurrency=1")
+	s.releaseCache("--concurrency=1")
+	s.releaseCache("--concurrency=2")
+
+	assert.Equal(t, 0, len(s.caches))
+	assert.False(t, c.values == s.acquireCache("--concurrency=1").values)
+}
+
+func TestServerDeleteFinishedJobs(t *testing.T) {
+	s := newServer()
+	s.jobTTL = time.Millisecond
+	h := httptest.NewServer(s)
+	defer h.Close()
+
+	createTestJob(t, h, `{"url":"`+rootURL+`"}`).Body.Close()
+
+	for i := 0; i < 100; i++ {
+		if _, ok := s.job("1"); !ok {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	_, ok := s.job("1")
+	assert.False(t, ok)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	assert.Equal(t, 0, len(s.caches))
+}
+
+func createTestJob(t *testing.T, s *httptest.Server, b string) *http.Response {
+	r, err := http.Post(s.URL+"/jobs", "application/json", strings.NewReader(b))
+	assert.Nil(t, err)
+
+	return r
+}
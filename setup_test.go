@@ -1,6 +1,7 @@
 package muffet
 
 import (
+	"compress/gzip"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -17,30 +19,45 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/japanese"
 )
 
 const (
-	rootURL             = "http://localhost:8080"
-	existentURL         = "http://localhost:8080/foo"
-	nonExistentURL      = "http://localhost:8080/bar"
-	erroneousURL        = "http://localhost:8080/erroneous"
-	fragmentURL         = "http://localhost:8080/fragment"
-	existentIDURL       = "http://localhost:8080/fragment#foo"
-	nonExistentIDURL    = "http://localhost:8080/fragment#bar"
-	baseURL             = "http://localhost:8080/base"
-	invalidBaseURL      = "http://localhost:8080/invalid-base"
-	redirectURL         = "http://localhost:8080/redirect"
-	infiniteRedirectURL = "http://localhost:8080/infinite-redirect"
-	invalidRedirectURL  = "http://localhost:8080/invalid-redirect"
-	timeoutURL          = "http://localhost:8080/timeout"
-	basicAuthURL        = "http://localhost:8080/basic-auth"
-	robotsTxtURL        = "http://localhost:8080/robots.txt"
-	missingMetadataURL  = "http://localhost:8081"
-	invalidRobotsTxtURL = "http://localhost:8082"
-	invalidMIMETypeURL  = "http://localhost:8083"
-	countingURL         = "http://localhost:8084"
-	selfCertificateURL  = "https://localhost:8085"
-	noResponseURL       = "http://localhost:8086"
+	rootURL                     = "http://localhost:8080"
+	existentURL                 = "http://localhost:8080/foo"
+	nonExistentURL              = "http://localhost:8080/bar"
+	erroneousURL                = "http://localhost:8080/erroneous"
+	fragmentURL                 = "http://localhost:8080/fragment"
+	existentIDURL               = "http://localhost:8080/fragment#foo"
+	nonExistentIDURL            = "http://localhost:8080/fragment#bar"
+	baseURL                     = "http://localhost:8080/base"
+	invalidBaseURL              = "http://localhost:8080/invalid-base"
+	redirectURL                 = "http://localhost:8080/redirect"
+	permanentRedirectURL        = "http://localhost:8080/permanent-redirect"
+	crossHostRedirectURL        = "http://localhost:8080/cross-host-redirect"
+	insecureRedirectURL         = "https://localhost:8085/insecure-redirect"
+	infiniteRedirectURL         = "http://localhost:8080/infinite-redirect"
+	redirectLoopURL             = "http://localhost:8080/redirect-loop"
+	redirectLoopBackURL         = "http://localhost:8080/redirect-loop-back"
+	relativeRedirectURL         = "http://localhost:8080/relative-redirect/"
+	protocolRelativeRedirectURL = "http://localhost:8080/protocol-relative-redirect"
+	longRedirectURL             = "http://localhost:8080/long-redirect/0"
+	invalidRedirectURL          = "http://localhost:8080/invalid-redirect"
+	timeoutURL                  = "http://localhost:8080/timeout"
+	basicAuthURL                = "http://localhost:8080/basic-auth"
+	browserOnlyURL              = "http://localhost:8080/browser-only"
+	shiftJISURL                 = "http://localhost:8080/shift-jis"
+	largePageURL                = "http://localhost:8080/large"
+	largeFileURL                = "http://localhost:8080/large.bin"
+	robotsTxtURL                = "http://localhost:8080/robots.txt"
+	missingMetadataURL          = "http://localhost:8081"
+	invalidRobotsTxtURL         = "http://localhost:8082"
+	invalidMIMETypeURL          = "http://localhost:8083"
+	countingURL                 = "http://localhost:8084"
+	selfCertificateURL          = "https://localhost:8085"
+	noResponseURL               = "http://localhost:8086"
+	soft404URL                  = "http://localhost:8087"
+	sitemapCheckURL             = "http://localhost:8088"
 )
 
 type handler struct{}
@@ -89,13 +106,65 @@ func (handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "/redirect":
 		w.Header().Add("Location", "/")
 		w.WriteHeader(300)
+	case "/permanent-redirect":
+		w.Header().Add("Location", "/redirect")
+		w.WriteHeader(301)
+	case "/cross-host-redirect":
+		w.Header().Add("Location", missingMetadataURL)
+		w.WriteHeader(302)
+	case "/insecure-redirect":
+		w.Header().Add("Location", rootURL)
+		w.WriteHeader(302)
 	case "/infinite-redirect":
 		w.Header().Add("Location", "/infinite-redirect")
 		w.WriteHeader(300)
+	case "/redirect-loop":
+		w.Header().Add("Location", "/redirect-loop-back")
+		w.WriteHeader(302)
+	case "/redirect-loop-back":
+		w.Header().Add("Location", "redirect-loop")
+		w.WriteHeader(302)
+	case "/relative-redirect/":
+		w.Header().Add("Location", "../foo")
+		w.WriteHeader(302)
+	case "/protocol-relative-redirect":
+		w.Header().Add("Location", "//localhost:8081/")
+		w.WriteHeader(302)
 	case "/invalid-redirect":
 		w.WriteHeader(300)
 	case "/timeout":
 		time.Sleep(10 * time.Second)
+	case "/browser-only":
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "Mozilla/") {
+			w.WriteHeader(403)
+			return
+		}
+
+		bs := []byte(htmlWithBody(`<a id="foo" href="#foo" />`))
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Add("Content-Encoding", "gzip")
+			z := gzip.NewWriter(w)
+			z.Write(bs)
+			z.Close()
+			return
+		}
+
+		w.Write(bs)
+	case "/shift-jis":
+		s, err := japanese.ShiftJIS.NewEncoder().String(htmlWithBody(`<a id="日本語" href="#日本語" />`))
+
+		if err != nil {
+			panic(err)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+		w.Write([]byte(s))
+	case "/large":
+		w.Write([]byte(htmlWithBody(strings.Repeat("<p>foo</p>", 1024) + `<p id="end" />`)))
+	case "/large.bin":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(make([]byte, 64*1024))
 	case "/basic-auth":
 		ss := strings.Split(r.Header.Get("Authorization"), " ")
 
@@ -136,6 +205,9 @@ func (handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			User-agent: *
 			Disallow: %v
 			Disallow: %v
+
+			User-agent: strict
+			Disallow: /
 		`, u.Path, v.Path)))
 	case "/sitemap.xml":
 		w.Header().Add("Content-Type", "text/xml")
@@ -155,6 +227,18 @@ func (handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			</urlset>
 		`, rootURL, existentURL)))
 	default:
+		if strings.HasPrefix(r.URL.Path, "/long-redirect/") {
+			i, err := strconv.Atoi(path.Base(r.URL.Path))
+
+			if err != nil {
+				panic(err)
+			}
+
+			w.Header().Add("Location", fmt.Sprintf("/long-redirect/%v", i+1))
+			w.WriteHeader(302)
+			return
+		}
+
 		w.WriteHeader(404)
 	}
 }
@@ -194,6 +278,114 @@ func (invalidMIMETypeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	w.Header().Add("Content-Type", ";")
 }
 
+type soft404Handler struct{}
+
+// nolint:errcheck
+func (soft404Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/html")
+
+	switch r.URL.Path {
+	case "", "/":
+		w.Write([]byte(`<html><head><title>Home</title></head><body>Welcome home!</body></html>`))
+	case "/foo":
+		w.Write([]byte(`<html><head><title>Foo</title></head><body>Foo is here.</body></html>`))
+	case "/moved":
+		w.Header().Add("Location", "/")
+		w.WriteHeader(302)
+	default:
+		w.Write([]byte(fmt.Sprintf(`
+			<html>
+				<head><title>Page not found</title><script>var x = 42;</script></head>
+				<body>
+					<h1>Sorry, the page %v was not found.</h1>
+					<p>%v</p>
+				</body>
+			</html>
+		`, r.URL.Path, loremIpsum)))
+	}
+}
+
+type sitemapHandler struct{}
+
+// nolint:errcheck
+func (sitemapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/html")
+
+	switch r.URL.Path {
+	case "", "/":
+		w.Write([]byte(htmlWithBody(`<a href="/foo" /><a href="/bar" />`)))
+	case "/foo", "/bar", "/baz":
+	case "/redirect":
+		w.Header().Add("Location", "/foo")
+		w.WriteHeader(302)
+	case "/image.png":
+		w.Header().Set("Content-Type", "image/png")
+	case "/robots.txt":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(fmt.Sprintf(`
+			User-agent: *
+			Sitemap: %[1]v/sitemap.xml
+			Sitemap: %[1]v/sitemap-gzipped.xml.gz
+			Sitemap: %[1]v/sitemap-missing.xml
+		`, sitemapCheckURL)))
+	case "/sitemap-gzipped.xml.gz":
+		w.Header().Set("Content-Type", "application/gzip")
+		g := gzip.NewWriter(w)
+		g.Write([]byte(fmt.Sprintf(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>%v/baz</loc></url>
+			</urlset>
+		`, sitemapCheckURL)))
+		g.Close()
+	case "/sitemap.xml":
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(fmt.Sprintf(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<sitemap><loc>%[1]v/sitemap-pages.xml</loc></sitemap>
+				<sitemap><loc>%[1]v/sitemap-index.xml</loc></sitemap>
+			</sitemapindex>
+		`, sitemapCheckURL)))
+	case "/sitemap-index.xml":
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(fmt.Sprintf(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<sitemap><loc>%[1]v/sitemap-pages.xml</loc></sitemap>
+				<sitemap><loc>%[1]v/sitemap-others.xml</loc></sitemap>
+			</sitemapindex>
+		`, sitemapCheckURL)))
+	case "/sitemap-pages.xml":
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(fmt.Sprintf(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>%[1]v/</loc></url>
+				<url><loc>%[1]v/foo</loc></url>
+				<url><loc>%[1]v/foo</loc></url>
+			</urlset>
+		`, sitemapCheckURL)))
+	case "/sitemap-others.xml":
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(fmt.Sprintf(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>%[1]v/redirect</loc></url>
+				<url><loc>%[1]v/image.png</loc></url>
+				<url><loc>%[1]v/missing</loc></url>
+				<url><loc>%[2]v</loc></url>
+			</urlset>
+		`, sitemapCheckURL, rootURL)))
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+const loremIpsum = `Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod
+tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation
+ullamco laboris nisi ut aliquip ex ea commodo consequat.`
+
 func htmlWithBody(b string) string {
 	return fmt.Sprintf(`<html><body>%v</body></html>`, b)
 }
@@ -218,6 +410,8 @@ func TestMain(m *testing.M) {
 	go http.ListenAndServe(":8082", invalidRobotsTxtHandler{})
 	go http.ListenAndServe(":8083", invalidMIMETypeHandler{})
 	go http.ListenAndServe(":8084", testCountingHandler)
+	go http.ListenAndServe(":8087", soft404Handler{})
+	go http.ListenAndServe(":8088", sitemapHandler{})
 
 	f, g, err := prepareTLSServer(":8085")
 	defer g()
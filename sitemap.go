@@ -0,0 +1,253 @@
+package muffet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/temoto/robotstxt"
+	"github.com/valyala/fasthttp"
+	"github.com/yterajima/go-sitemap"
+)
+
+const (
+	// maxSitemapDepth is a maximum depth of nested sitemap indexes.
+	maxSitemapDepth = 8
+	// maxSitemapSize is a maximum size of a sitemap both in responses and
+	// after decompression as per the sitemap protocol.
+	maxSitemapSize = 50 << 20
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// sitemapEntry is a URL listed in a sitemap.
+type sitemapEntry struct {
+	url     string
+	sitemap string
+}
+
+func newSitemapEntry(u, s string) sitemapEntry {
+	return sitemapEntry{u, s}
+}
+
+func (e sitemapEntry) URL() string {
+	return e.url
+}
+
+// Sitemap returns a URL of a sitemap listing an entry.
+func (e sitemapEntry) Sitemap() string {
+	return e.sitemap
+}
+
+// sitemapClient fetches robots.txt and sitemaps with the same timeout and
+// headers as other requests.
+type sitemapClient struct {
+	client  *fasthttp.Client
+	options fetcherOptions
+}
+
+func newSitemapClient(c *fasthttp.Client, o fetcherOptions) sitemapClient {
+	o.Initialize()
+
+	return sitemapClient{c, o}
+}
+
+// Get fetches a file following redirections and returns its status code and
+// body decoded as per its content encoding.
+func (c sitemapClient) Get(s string) (int, []byte, error) {
+	req, res := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(s)
+
+	hs := map[string]string(nil)
+
+	for i := 0; ; i++ {
+		hs = replaceRequestHeaders(&req.Header, hs, c.options.RequestHeaders(string(req.URI().Host())))
+
+		if err := c.client.DoTimeout(req, res, c.options.Timeout); err != nil {
+			return 0, nil, err
+		}
+
+		bs := res.Header.Peek("Location")
+
+		if res.StatusCode()/100 != 3 || len(bs) == 0 {
+			break
+		} else if i >= c.options.MaxRedirections {
+			return 0, nil, fmt.Errorf("too many redirections at %v", s)
+		}
+
+		l, err := resolveLocation(req.URI().String(), string(bs))
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		req.URI().Update(l)
+	}
+
+	bs, err := decodeContent(res.Body(), string(res.Header.Peek("Content-Encoding")), maxSitemapSize)
+
+	if err == errBodyTooLarge {
+		return 0, nil, fmt.Errorf("sitemap larger than %v bytes at %v", maxSitemapSize, s)
+	} else if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode(), bs, nil
+}
+
+// sitemapURL returns a URL of sitemap.xml at the root of a website.
+func sitemapURL(s string) (string, error) {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return "", err
+	}
+
+	return u.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String(), nil
+}
+
+// discoverSitemaps returns URLs of sitemaps of a website. Sitemaps given
+// explicitly take precedence over ones declared in robots.txt, which take
+// precedence over sitemap.xml at the root.
+func discoverSitemaps(c sitemapClient, s string, ss []string) ([]string, error) {
+	if len(ss) != 0 {
+		return ss, nil
+	}
+
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, bs, err := c.Get(u.ResolveReference(&url.URL{Path: "/robots.txt"}).String())
+
+	// Sitemaps at the root are tried on any errors of robots.txt.
+	if err == nil && n == 200 {
+		if r, err := robotstxt.FromBytes(bs); err == nil && len(r.Sitemaps) != 0 {
+			return r.Sitemaps, nil
+		}
+	}
+
+	m, err := sitemapURL(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{m}, nil
+}
+
+// loadSitemaps loads entries in sitemaps following nested sitemap indexes in
+// order. It also returns URLs of sitemaps not found.
+func loadSitemaps(c sitemapClient, ss []string) ([]sitemapEntry, []string, error) {
+	es, ms, vs := []sitemapEntry(nil), []string(nil), map[string]struct{}{}
+
+	for _, s := range ss {
+		fs, err := loadSitemap(c, s, vs, &ms, 0)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		es = append(es, fs...)
+	}
+
+	return es, ms, nil
+}
+
+func loadSitemap(c sitemapClient, s string, vs map[string]struct{}, ms *[]string, d int) ([]sitemapEntry, error) {
+	if d > maxSitemapDepth {
+		return nil, fmt.Errorf("too deep sitemap indexes at %v", s)
+	} else if _, ok := vs[s]; ok {
+		return nil, nil
+	}
+
+	vs[s] = struct{}{}
+
+	n, bs, err := c.Get(s)
+
+	if err != nil {
+		return nil, err
+	} else if n != 200 {
+		*ms = append(*ms, s)
+		return nil, nil
+	}
+
+	bs, err = decompressSitemap(bs)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid sitemap at %v: %v", s, err)
+	}
+
+	if i, err := sitemap.ParseIndex(bs); err == nil {
+		es := []sitemapEntry(nil)
+
+		for _, p := range i.Sitemap {
+			fs, err := loadSitemap(c, p.Loc, vs, ms, d+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			es = append(es, fs...)
+		}
+
+		return es, nil
+	}
+
+	m, err := sitemap.Parse(bs)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid sitemap at %v: %v", s, err)
+	}
+
+	es := make([]sitemapEntry, 0, len(m.URL))
+
+	for _, u := range m.URL {
+		es = append(es, newSitemapEntry(u.Loc, s))
+	}
+
+	return es, nil
+}
+
+// decompressSitemap decompresses a sitemap if it is gzipped. Sitemaps are
+// detected by their contents rather than extensions or headers as servers
+// often send them without Content-Encoding.
+func decompressSitemap(bs []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bs, gzipMagic) {
+		return bs, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(bs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err = ioutil.ReadAll(io.LimitReader(r, maxSitemapSize+1))
+
+	if err != nil {
+		return nil, err
+	} else if len(bs) > maxSitemapSize {
+		return nil, fmt.Errorf("sitemap larger than %v bytes", maxSitemapSize)
+	}
+
+	return bs, nil
+}
+
+func sitemapWarnings(ms []string) []string {
+	ws := make([]string, 0, len(ms))
+
+	for _, m := range ms {
+		ws = append(ws, fmt.Sprintf("sitemap not found at %v", m))
+	}
+
+	return ws
+}
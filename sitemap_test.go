@@ -0,0 +1,195 @@
+package muffet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSitemapClientGet(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		g := gzip.NewWriter(w)
+		g.Write([]byte(r.Header.Get("User-Agent") + " " + r.Header.Get("X-Foo")))
+		g.Close()
+	}))
+	defer s.Close()
+
+	n, bs, err := newSitemapClient(
+		&fasthttp.Client{},
+		fetcherOptions{UserAgent: "foo", Headers: map[string]string{"X-Foo": "bar"}},
+	).Get(s.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 200, n)
+	assert.Equal(t, "foo bar", string(bs))
+}
+
+func TestSitemapClientGetWithTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer s.Close()
+
+	_, _, err := newSitemapClient(&fasthttp.Client{}, fetcherOptions{Timeout: time.Millisecond}).Get(s.URL)
+	assert.NotNil(t, err)
+}
+
+func TestSitemapClientGetWithRedirections(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/foo":
+			http.Redirect(w, r, "/bar", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.Write([]byte(r.URL.Path + " " + r.Header.Get("User-Agent")))
+		}
+	}))
+	defer s.Close()
+
+	c := newSitemapClient(&fasthttp.Client{}, fetcherOptions{UserAgent: "foo"})
+	n, bs, err := c.Get(s.URL + "/foo")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 200, n)
+	assert.Equal(t, "/bar foo", string(bs))
+
+	_, _, err = c.Get(s.URL + "/loop")
+	assert.NotNil(t, err)
+}
+
+func TestSitemapURL(t *testing.T) {
+	for _, c := range [][2]string{
+		{"https://foo.com", "https://foo.com/sitemap.xml"},
+		{"https://foo.com/", "https://foo.com/sitemap.xml"},
+		{"https://foo.com/foo/bar?baz#qux", "https://foo.com/sitemap.xml"},
+	} {
+		s, err := sitemapURL(c[0])
+
+		assert.Nil(t, err)
+		assert.Equal(t, c[1], s)
+	}
+}
+
+func TestSitemapURLError(t *testing.T) {
+	_, err := sitemapURL(":")
+	assert.NotNil(t, err)
+}
+
+func TestDiscoverSitemaps(t *testing.T) {
+	for _, c := range []struct {
+		url      string
+		sitemaps []string
+		answer   []string
+	}{
+		{rootURL, []string{"https://foo.com/sitemap.xml"}, []string{"https://foo.com/sitemap.xml"}},
+		{existentURL, nil, []string{rootURL + "/sitemap.xml"}},
+		{missingMetadataURL, nil, []string{missingMetadataURL + "/sitemap.xml"}},
+		{noResponseURL, nil, []string{noResponseURL + "/sitemap.xml"}},
+		{
+			sitemapCheckURL + "/foo",
+			nil,
+			[]string{
+				sitemapCheckURL + "/sitemap.xml",
+				sitemapCheckURL + "/sitemap-gzipped.xml.gz",
+				sitemapCheckURL + "/sitemap-missing.xml",
+			},
+		},
+	} {
+		ss, err := discoverSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), c.url, c.sitemaps)
+
+		assert.Nil(t, err)
+		assert.Equal(t, c.answer, ss)
+	}
+}
+
+func TestDiscoverSitemapsError(t *testing.T) {
+	_, err := discoverSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), ":", nil)
+	assert.NotNil(t, err)
+}
+
+func TestLoadSitemaps(t *testing.T) {
+	es, ms, err := loadSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), []string{rootURL + "/sitemap.xml"})
+
+	assert.Nil(t, err)
+	assert.Nil(t, ms)
+	assert.Equal(t, []sitemapEntry{
+		newSitemapEntry(rootURL, rootURL+"/sitemap.xml"),
+		newSitemapEntry(existentURL, rootURL+"/sitemap.xml"),
+	}, es)
+}
+
+func TestLoadSitemapsWithIndexes(t *testing.T) {
+	es, ms, err := loadSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), []string{
+		sitemapCheckURL + "/sitemap.xml",
+		sitemapCheckURL + "/sitemap-gzipped.xml.gz",
+		sitemapCheckURL + "/sitemap-missing.xml",
+	})
+	assert.Nil(t, err)
+
+	us := []string{}
+
+	for _, e := range es {
+		us = append(us, e.URL())
+	}
+
+	assert.Equal(t, []string{
+		sitemapCheckURL + "/",
+		sitemapCheckURL + "/foo",
+		sitemapCheckURL + "/foo",
+		sitemapCheckURL + "/redirect",
+		sitemapCheckURL + "/image.png",
+		sitemapCheckURL + "/missing",
+		rootURL,
+		sitemapCheckURL + "/baz",
+	}, us)
+	assert.Equal(t, sitemapCheckURL+"/sitemap-others.xml", es[len(es)-2].Sitemap())
+	assert.Equal(t, []string{sitemapCheckURL + "/sitemap-missing.xml"}, ms)
+}
+
+func TestLoadSitemapsWithMissingSitemap(t *testing.T) {
+	es, ms, err := loadSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), []string{missingMetadataURL + "/sitemap.xml"})
+
+	assert.Nil(t, err)
+	assert.Nil(t, es)
+	assert.Equal(t, []string{missingMetadataURL + "/sitemap.xml"}, ms)
+}
+
+func TestLoadSitemapsError(t *testing.T) {
+	for _, s := range []string{existentURL, noResponseURL + "/sitemap.xml"} {
+		_, _, err := loadSitemaps(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), []string{s})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestDecompressSitemap(t *testing.T) {
+	bs, err := decompressSitemap([]byte("foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", string(bs))
+
+	b := &bytes.Buffer{}
+	w := gzip.NewWriter(b)
+	_, err = w.Write([]byte("foo"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	bs, err = decompressSitemap(b.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", string(bs))
+}
+
+func TestDecompressSitemapError(t *testing.T) {
+	_, err := decompressSitemap(append(append([]byte(nil), gzipMagic...), "foo"...))
+	assert.NotNil(t, err)
+}
+
+func TestSitemapWarnings(t *testing.T) {
+	assert.Equal(t, []string{"sitemap not found at foo"}, sitemapWarnings([]string{"foo"}))
+}
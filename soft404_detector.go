@@ -0,0 +1,183 @@
+package muffet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// soft404Similarity is a minimum similarity of pages to a page for a
+// nonexistent URL for them to be considered as soft 404 pages.
+const soft404Similarity = 0.9
+
+type soft404Pattern struct {
+	host   string
+	regexp *regexp.Regexp
+}
+
+func newSoft404Pattern(h string, r *regexp.Regexp) soft404Pattern {
+	return soft404Pattern{h, r}
+}
+
+func (p soft404Pattern) Match(u *url.URL, s string) bool {
+	return (p.host == "*" || p.host == u.Hostname()) && p.regexp.MatchString(s)
+}
+
+// document is a summary of an HTML page used to compare it with others.
+type document struct {
+	url, title, text string
+	words            map[string]struct{}
+}
+
+func newDocument(u string, n *html.Node) document {
+	t := ""
+
+	if n, ok := scrape.Find(n, scrape.ByTag(atom.Title)); ok {
+		t = strings.TrimSpace(scrape.Text(n))
+	}
+
+	ss := []string(nil)
+
+	for _, n := range scrape.FindAllNested(n, func(n *html.Node) bool {
+		return n.Type == html.TextNode && !isInvisibleText(n)
+	}) {
+		ss = append(ss, strings.Fields(n.Data)...)
+	}
+
+	ws := map[string]struct{}{}
+
+	for _, s := range append(strings.Fields(t), ss...) {
+		ws[s] = struct{}{}
+	}
+
+	return document{u, t, strings.Join(ss, " "), ws}
+}
+
+// Similarity returns the Jaccard index of words in two documents.
+func (d document) Similarity(e document) float64 {
+	if len(d.words) == 0 && len(e.words) == 0 {
+		return 1
+	}
+
+	i := 0
+
+	for w := range d.words {
+		if _, ok := e.words[w]; ok {
+			i++
+		}
+	}
+
+	return float64(i) / float64(len(d.words)+len(e.words)-i)
+}
+
+type soft404Detector struct {
+	titlePatterns, bodyPatterns []soft404Pattern
+	probe                       bool
+	probes                      cache
+}
+
+func newSoft404Detector(ts, bs []soft404Pattern, p bool) soft404Detector {
+	return soft404Detector{ts, bs, p, newCache()}
+}
+
+func (d soft404Detector) Enabled() bool {
+	return len(d.titlePatterns) != 0 || len(d.bodyPatterns) != 0 || d.probe
+}
+
+// Detect checks if a page is a soft 404 page. A redirected argument tells
+// if a request for the page was redirected. A fetch function is used to get
+// a page for a nonexistent URL on the same host.
+func (d soft404Detector) Detect(
+	s string, n *html.Node, redirected bool, fetch func(string) (document, error),
+) error {
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return err
+	}
+
+	doc := newDocument(s, n)
+
+	for _, p := range d.titlePatterns {
+		if p.Match(u, doc.title) {
+			return newSoft404Error(fmt.Sprintf("title matches /%v/", p.regexp))
+		}
+	}
+
+	for _, p := range d.bodyPatterns {
+		if p.Match(u, doc.text) {
+			return newSoft404Error(fmt.Sprintf("body matches /%v/", p.regexp))
+		}
+	}
+
+	if !d.probe {
+		return nil
+	}
+
+	q, ok := d.probeHost(u, fetch)
+
+	// Pages reached directly at the same URL as the probe's landing page,
+	// such as top pages, are not soft 404 pages.
+	if !ok || !redirected && q.url == s {
+		return nil
+	}
+
+	if doc.Similarity(q) >= soft404Similarity {
+		return newSoft404Error("similar to a page for a nonexistent URL")
+	}
+
+	return nil
+}
+
+func (d soft404Detector) probeHost(u *url.URL, fetch func(string) (document, error)) (document, bool) {
+	k := u.Scheme + "://" + u.Host
+	x, store, ok := d.probes.LoadOrStore(k)
+
+	if ok {
+		doc, ok := x.(document)
+		return doc, ok
+	}
+
+	doc, err := fetch(k + "/" + randomPath())
+
+	if err != nil {
+		store(err)
+		return document{}, false
+	}
+
+	store(doc)
+	return doc, true
+}
+
+func newSoft404Error(s string) fetchError {
+	return newFetchError(ErrorKindSoft404, errors.New("soft 404: "+s))
+}
+
+func isInvisibleText(n *html.Node) bool {
+	for n = n.Parent; n != nil; n = n.Parent {
+		switch n.DataAtom {
+		case atom.Head, atom.Script, atom.Style, atom.Noscript, atom.Template:
+			return true
+		}
+	}
+
+	return false
+}
+
+func randomPath() string {
+	bs := make([]byte, 16)
+
+	if _, err := rand.Read(bs); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(bs)
+}
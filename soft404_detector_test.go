@@ -0,0 +1,135 @@
+package muffet
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestSoft404PatternMatch(t *testing.T) {
+	u, err := url.Parse("https://foo.com/bar")
+	assert.Nil(t, err)
+
+	r := regexp.MustCompile("not found")
+
+	assert.True(t, newSoft404Pattern("foo.com", r).Match(u, "page not found"))
+	assert.True(t, newSoft404Pattern("*", r).Match(u, "page not found"))
+	assert.False(t, newSoft404Pattern("bar.com", r).Match(u, "page not found"))
+	assert.False(t, newSoft404Pattern("foo.com", r).Match(u, "page found"))
+}
+
+func TestNewDocument(t *testing.T) {
+	n, err := html.Parse(strings.NewReader(`
+		<html>
+			<head><title> Foo </title><style>p {}</style></head>
+			<body><p>Hello, world!</p><script>var bar;</script></body>
+		</html>
+	`))
+	assert.Nil(t, err)
+
+	d := newDocument("https://foo.com", n)
+
+	assert.Equal(t, "Foo", d.title)
+	assert.Equal(t, "Hello, world!", d.text)
+	assert.Equal(t, map[string]struct{}{"Foo": {}, "Hello,": {}, "world!": {}}, d.words)
+}
+
+func TestDocumentSimilarity(t *testing.T) {
+	d := document{words: map[string]struct{}{"foo": {}, "bar": {}}}
+	e := document{words: map[string]struct{}{"foo": {}, "baz": {}}}
+
+	assert.Equal(t, 1.0, d.Similarity(d))
+	assert.Equal(t, 1.0/3, d.Similarity(e))
+	assert.Equal(t, 1.0, document{}.Similarity(document{}))
+	assert.Equal(t, 0.0, d.Similarity(document{}))
+}
+
+func TestSoft404DetectorEnabled(t *testing.T) {
+	p := newSoft404Pattern("*", regexp.MustCompile("foo"))
+
+	assert.False(t, newSoft404Detector(nil, nil, false).Enabled())
+	assert.True(t, newSoft404Detector([]soft404Pattern{p}, nil, false).Enabled())
+	assert.True(t, newSoft404Detector(nil, []soft404Pattern{p}, false).Enabled())
+	assert.True(t, newSoft404Detector(nil, nil, true).Enabled())
+}
+
+func TestSoft404DetectorDetectWithPatterns(t *testing.T) {
+	n, err := html.Parse(strings.NewReader(
+		`<html><head><title>Oops</title></head><body>Page not found</body></html>`))
+	assert.Nil(t, err)
+
+	fetch := func(string) (document, error) { panic("unreachable") }
+
+	for _, c := range []struct {
+		detector soft404Detector
+		error    string
+	}{
+		{
+			newSoft404Detector([]soft404Pattern{newSoft404Pattern("foo.com", regexp.MustCompile("Oops"))}, nil, false),
+			"soft 404: title matches /Oops/",
+		},
+		{
+			newSoft404Detector(nil, []soft404Pattern{newSoft404Pattern("*", regexp.MustCompile("not found"))}, false),
+			"soft 404: body matches /not found/",
+		},
+	} {
+		err := c.detector.Detect("https://foo.com/bar", n, false, fetch)
+
+		assert.Equal(t, c.error, err.Error())
+		assert.Equal(t, ErrorKindSoft404, errorKindOf(err))
+	}
+
+	assert.Nil(t, newSoft404Detector(
+		[]soft404Pattern{newSoft404Pattern("bar.com", regexp.MustCompile("Oops"))},
+		[]soft404Pattern{newSoft404Pattern("*", regexp.MustCompile("Welcome"))},
+		false,
+	).Detect("https://foo.com/bar", n, false, fetch))
+}
+
+func TestSoft404DetectorDetectWithProbe(t *testing.T) {
+	d := newSoft404Detector(nil, nil, true)
+	i := 0
+	fetch := func(s string) (document, error) {
+		i++
+
+		assert.True(t, strings.HasPrefix(s, "https://foo.com/"))
+
+		n, err := html.Parse(strings.NewReader(htmlWithBody("Page " + s + " not found. " + loremIpsum)))
+		assert.Nil(t, err)
+
+		return newDocument("https://foo.com/404", n), nil
+	}
+
+	n, err := html.Parse(strings.NewReader(htmlWithBody("Page https://foo.com/bar not found. " + loremIpsum)))
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrorKindSoft404, errorKindOf(d.Detect("https://foo.com/bar", n, false, fetch)))
+	assert.Nil(t, d.Detect("https://foo.com/404", n, false, fetch))
+	assert.NotNil(t, d.Detect("https://foo.com/404", n, true, fetch))
+
+	n, err = html.Parse(strings.NewReader(htmlWithBody("Hello!")))
+	assert.Nil(t, err)
+
+	assert.Nil(t, d.Detect("https://foo.com/baz", n, false, fetch))
+	assert.Equal(t, 1, i)
+}
+
+func TestSoft404DetectorDetectWithFailedProbe(t *testing.T) {
+	n, err := html.Parse(strings.NewReader(htmlWithBody("Page not found")))
+	assert.Nil(t, err)
+
+	assert.Nil(t, newSoft404Detector(nil, nil, true).Detect(
+		"https://foo.com/bar",
+		n,
+		false,
+		func(string) (document, error) { return document{}, errors.New("404") }))
+}
+
+func TestSoft404DetectorDetectError(t *testing.T) {
+	assert.NotNil(t, newSoft404Detector(nil, nil, true).Detect(":", dummyHTML(t), false, nil))
+}
@@ -0,0 +1,83 @@
+package muffet
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// statistics are live counters of a check shared by a checker and a fetcher.
+type statistics struct {
+	pagesFound, pagesChecked, linksChecked, errors, requests int64
+	mutex                                                    *sync.Mutex
+	inFlightRequests                                         map[string]int
+}
+
+func newStatistics() *statistics {
+	return &statistics{mutex: &sync.Mutex{}, inFlightRequests: map[string]int{}}
+}
+
+func (s *statistics) AddPages(n int) {
+	atomic.AddInt64(&s.pagesFound, int64(n))
+}
+
+// AddResult counts a checked page and its links.
+func (s *statistics) AddResult(r pageResult) {
+	atomic.AddInt64(&s.pagesChecked, 1)
+	atomic.AddInt64(&s.linksChecked, int64(len(r.Links())))
+
+	for _, l := range r.Links() {
+		if !l.OK() && !l.Ignored() {
+			atomic.AddInt64(&s.errors, 1)
+		}
+	}
+}
+
+// StartRequest counts a request to a host and returns a function to be called
+// when it finishes.
+func (s *statistics) StartRequest(h string) func() {
+	atomic.AddInt64(&s.requests, 1)
+
+	s.mutex.Lock()
+	s.inFlightRequests[h]++
+	s.mutex.Unlock()
+
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if s.inFlightRequests[h]--; s.inFlightRequests[h] == 0 {
+			delete(s.inFlightRequests, h)
+		}
+	}
+}
+
+func (s *statistics) Snapshot() statisticsSnapshot {
+	s.mutex.Lock()
+	m := make(map[string]int, len(s.inFlightRequests))
+
+	for h, n := range s.inFlightRequests {
+		m[h] = n
+	}
+
+	s.mutex.Unlock()
+
+	return statisticsSnapshot{
+		int(atomic.LoadInt64(&s.pagesFound)),
+		int(atomic.LoadInt64(&s.pagesChecked)),
+		int(atomic.LoadInt64(&s.linksChecked)),
+		int(atomic.LoadInt64(&s.errors)),
+		int(atomic.LoadInt64(&s.requests)),
+		m,
+	}
+}
+
+// statisticsSnapshot is a state of statistics at a moment.
+type statisticsSnapshot struct {
+	PagesFound, PagesChecked, LinksChecked, Errors, Requests int
+	InFlightRequests                                         map[string]int
+}
+
+// PagesQueued returns a number of pages found but not checked yet.
+func (s statisticsSnapshot) PagesQueued() int {
+	return s.PagesFound - s.PagesChecked
+}
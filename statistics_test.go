@@ -0,0 +1,53 @@
+package muffet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatisticsAddResult(t *testing.T) {
+	s := newStatistics()
+	s.AddPages(2)
+	s.AddResult(newPageResult("foo", []linkResult{
+		newLinkResult("bar", 200, nil),
+		newLinkResult("baz", 0, newHTTPStatusError(404)),
+		newLinkResult("qux", 0, errors.New("qux")).Ignore(),
+	}))
+
+	x := s.Snapshot()
+
+	assert.Equal(t, 2, x.PagesFound)
+	assert.Equal(t, 1, x.PagesChecked)
+	assert.Equal(t, 1, x.PagesQueued())
+	assert.Equal(t, 3, x.LinksChecked)
+	assert.Equal(t, 1, x.Errors)
+}
+
+func TestStatisticsStartRequest(t *testing.T) {
+	s := newStatistics()
+	f := s.StartRequest("foo.com")
+	g := s.StartRequest("foo.com")
+
+	assert.Equal(t, map[string]int{"foo.com": 2}, s.Snapshot().InFlightRequests)
+
+	f()
+	assert.Equal(t, map[string]int{"foo.com": 1}, s.Snapshot().InFlightRequests)
+
+	g()
+
+	x := s.Snapshot()
+	assert.Equal(t, map[string]int{}, x.InFlightRequests)
+	assert.Equal(t, 2, x.Requests)
+}
+
+func TestStatisticsSnapshotCopiesInFlightRequests(t *testing.T) {
+	s := newStatistics()
+	defer s.StartRequest("foo.com")()
+
+	x := s.Snapshot()
+	x.InFlightRequests["foo.com"] = 42
+
+	assert.Equal(t, 1, s.Snapshot().InFlightRequests["foo.com"])
+}
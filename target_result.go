@@ -0,0 +1,110 @@
+package muffet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// targetResult is a broken link aggregated over pages referring to it.
+type targetResult struct {
+	url        string
+	statusCode int
+	err        error
+	pages      []string
+}
+
+// newTargetResults builds an inverse index from broken links to pages in page
+// results. Targets referred to by more pages come first.
+func newTargetResults(rs []pageResult) []targetResult {
+	m := map[string]*targetResult{}
+
+	for _, r := range rs {
+		for _, l := range r.Links() {
+			if l.OK() || l.Ignored() {
+				continue
+			}
+
+			t, ok := m[l.URL()]
+
+			if !ok {
+				t = &targetResult{l.URL(), l.StatusCode(), l.Error(), nil}
+				m[l.URL()] = t
+			}
+
+			t.pages = append(t.pages, r.URL())
+		}
+	}
+
+	ts := make([]targetResult, 0, len(m))
+
+	for _, t := range m {
+		sort.Strings(t.pages)
+		ts = append(ts, *t)
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		if len(ts[i].pages) != len(ts[j].pages) {
+			return len(ts[i].pages) > len(ts[j].pages)
+		}
+
+		return ts[i].url < ts[j].url
+	})
+
+	return ts
+}
+
+func (r targetResult) URL() string {
+	return r.url
+}
+
+func (r targetResult) StatusCode() int {
+	return r.statusCode
+}
+
+func (r targetResult) Error() error {
+	return r.err
+}
+
+func (r targetResult) ErrorKind() ErrorKind {
+	return errorKindOf(r.err)
+}
+
+// Pages returns URLs of pages referring to a target.
+func (r targetResult) Pages() []string {
+	return r.pages
+}
+
+func (r targetResult) String() string {
+	return strings.Join(
+		append(
+			[]string{
+				color.YellowString(r.url),
+				"\t" + color.RedString("%v [%v]", r.err, r.ErrorKind()) + "\t" + formatPageCount(len(r.pages)),
+			},
+			formatMessages(r.pages)...,
+		),
+		"\n")
+}
+
+func (r targetResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		URL        string    `json:"url"`
+		StatusCode int       `json:"status,omitempty"`
+		Error      string    `json:"error"`
+		ErrorKind  ErrorKind `json:"kind"`
+		Count      int       `json:"pageCount"`
+		Pages      []string  `json:"pages"`
+	}{r.url, r.statusCode, r.err.Error(), r.ErrorKind(), len(r.pages), r.pages})
+}
+
+func formatPageCount(n int) string {
+	if n == 1 {
+		return "1 page"
+	}
+
+	return fmt.Sprintf("%v pages", n)
+}
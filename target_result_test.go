@@ -0,0 +1,82 @@
+package muffet
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTargetResults(t *testing.T) {
+	e := newHTTPStatusError(404)
+
+	ts := newTargetResults([]pageResult{
+		newPageResult("b", []linkResult{
+			newLinkResult("foo", 404, e),
+			newLinkResult("bar", 404, e),
+			newLinkResult("baz", 200, nil),
+			newLinkResult("qux", 404, e).Ignore(),
+		}),
+		newPageResult("a", []linkResult{
+			newLinkResult("foo", 404, e),
+		}),
+	})
+
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, "foo", ts[0].URL())
+	assert.Equal(t, []string{"a", "b"}, ts[0].Pages())
+	assert.Equal(t, 404, ts[0].StatusCode())
+	assert.Equal(t, ErrorKindHTTPStatus, ts[0].ErrorKind())
+	assert.Equal(t, e, ts[0].Error())
+	assert.Equal(t, "bar", ts[1].URL())
+	assert.Equal(t, []string{"b"}, ts[1].Pages())
+}
+
+func TestNewTargetResultsWithoutBrokenLinks(t *testing.T) {
+	assert.Equal(t, []targetResult{}, newTargetResults([]pageResult{
+		newPageResult("a", []linkResult{newLinkResult("foo", 200, nil)}),
+	}))
+}
+
+func TestTargetResultString(t *testing.T) {
+	ts := newTargetResults([]pageResult{
+		newPageResult("a", []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}),
+		newPageResult("b", []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}),
+	})
+
+	s := ts[0].String()
+
+	assert.Equal(t, 3, strings.Count(s, "\n"))
+	assert.Contains(t, s, "2 pages")
+	assert.Contains(t, s, "http-status")
+}
+
+func TestTargetResultMarshalJSON(t *testing.T) {
+	ts := newTargetResults([]pageResult{
+		newPageResult("a", []linkResult{newLinkResult("foo", 404, newHTTPStatusError(404))}),
+	})
+
+	bs, err := json.Marshal(ts[0])
+	assert.Nil(t, err)
+
+	r := struct {
+		URL       string
+		Status    int
+		Kind      string
+		PageCount int
+		Pages     []string
+	}{}
+
+	assert.Nil(t, json.Unmarshal(bs, &r))
+	assert.Equal(t, "foo", r.URL)
+	assert.Equal(t, 404, r.Status)
+	assert.Equal(t, "http-status", r.Kind)
+	assert.Equal(t, 1, r.PageCount)
+	assert.Equal(t, []string{"a"}, r.Pages)
+}
+
+func TestFormatPageCount(t *testing.T) {
+	assert.Equal(t, "1 page", formatPageCount(1))
+	assert.Equal(t, "2 pages", formatPageCount(2))
+}
@@ -1,73 +1,54 @@
 package muffet
 
 import (
-	"errors"
 	"net/url"
-
-	"github.com/temoto/robotstxt"
-	"github.com/valyala/fasthttp"
-	"github.com/yterajima/go-sitemap"
 )
 
 type urlInspector struct {
 	hostname     string
 	includedURLs map[string]struct{}
-	robotsTxt    *robotstxt.RobotsData
+	robotsTxt    *robotsTxtCache
+	warnings     []string
 }
 
-func newURLInspector(c *fasthttp.Client, s string, r, sm bool) (urlInspector, error) {
+// newURLInspector creates an inspector of URLs on a website. robots.txt is not
+// followed if its cache is nil. Sitemaps are discovered if no URL of them is
+// given.
+func newURLInspector(c sitemapClient, s string, r *robotsTxtCache, sm bool, sms []string) (urlInspector, error) {
 	u, err := url.Parse(s)
 
 	if err != nil {
 		return urlInspector{}, err
 	}
 
-	rd := (*robotstxt.RobotsData)(nil)
+	us, ws := map[string]struct{}{}, []string(nil)
 
-	if r {
-		u.Path = "robots.txt"
-		c, bs, err := c.Get(nil, u.String())
+	if sm {
+		ss, err := discoverSitemaps(c, s, sms)
 
 		if err != nil {
 			return urlInspector{}, err
-		} else if c != 200 {
-			return urlInspector{}, errors.New("robots.txt not found")
 		}
 
-		rd, err = robotstxt.FromBytes(bs)
+		es, ms, err := loadSitemaps(c, ss)
 
 		if err != nil {
 			return urlInspector{}, err
 		}
-	}
-
-	us := map[string]struct{}{}
-
-	if sm {
-		u.Path = "sitemap.xml"
-
-		sitemap.SetFetch(func(s string, _ interface{}) ([]byte, error) {
-			c, bs, err := c.Get(nil, s)
-
-			if c != 200 {
-				return nil, errors.New("sitemap not found")
-			}
 
-			return bs, err
-		})
-
-		m, err := sitemap.Get(u.String(), nil)
-
-		if err != nil {
-			return urlInspector{}, err
+		for _, e := range es {
+			us[e.URL()] = struct{}{}
 		}
 
-		for _, u := range m.URL {
-			us[u.Loc] = struct{}{}
-		}
+		ws = sitemapWarnings(ms)
 	}
 
-	return urlInspector{u.Hostname(), us, rd}, nil
+	return urlInspector{u.Hostname(), us, r, ws}, nil
+}
+
+// Warnings returns warnings on loading metadata of a website.
+func (i urlInspector) Warnings() []string {
+	return i.warnings
 }
 
 func (i urlInspector) Inspect(u *url.URL) bool {
@@ -77,9 +58,9 @@ func (i urlInspector) Inspect(u *url.URL) bool {
 		}
 	}
 
-	if i.robotsTxt != nil && !i.robotsTxt.TestAgent(u.Path, "muffet") {
+	if u.Hostname() != i.hostname {
 		return false
 	}
 
-	return u.Hostname() == i.hostname
+	return i.robotsTxt == nil || i.robotsTxt.Allowed(u)
 }
@@ -10,48 +10,85 @@ import (
 )
 
 func TestNewURLInspector(t *testing.T) {
-	_, err := newURLInspector(&fasthttp.Client{}, rootURL, false, false)
+	_, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), rootURL, nil, false, nil)
 	assert.Nil(t, err)
 }
 
 func TestNewURLInspectorError(t *testing.T) {
-	_, err := newURLInspector(&fasthttp.Client{}, ":", false, false)
+	_, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), ":", nil, false, nil)
 	assert.NotNil(t, err)
 }
 
 func TestNewURLInspectorWithSitemapXML(t *testing.T) {
-	_, err := newURLInspector(&fasthttp.Client{}, rootURL, false, true)
+	_, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), rootURL, nil, true, nil)
 	assert.Nil(t, err)
 }
 
-func TestNewURLInspectorErrorWithRobotsTxt(t *testing.T) {
-	for _, s := range []string{missingMetadataURL, invalidRobotsTxtURL, noResponseURL} {
-		_, err := newURLInspector(&fasthttp.Client{}, s, true, false)
-		assert.NotNil(t, err)
+func TestNewURLInspectorWithMissingRobotsTxt(t *testing.T) {
+	r := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), defaultRobotsTxtUserAgent)
+
+	for _, s := range []string{missingMetadataURL, invalidRobotsTxtURL} {
+		i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), s, &r, false, nil)
+		assert.Nil(t, err)
+
+		u, err := url.Parse(s + "/foo")
+		assert.Nil(t, err)
+		assert.True(t, i.Inspect(u))
 	}
 }
 
 func TestNewURLInspectorWithMissingSitemapXML(t *testing.T) {
-	for _, s := range []string{missingMetadataURL, noResponseURL} {
-		_, err := newURLInspector(&fasthttp.Client{}, s, false, true)
-		assert.NotNil(t, err)
-	}
+	i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), missingMetadataURL, nil, true, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sitemap not found at " + missingMetadataURL + "/sitemap.xml"}, i.Warnings())
+
+	u, err := url.Parse(missingMetadataURL + "/foo")
+	assert.Nil(t, err)
+	assert.True(t, i.Inspect(u))
+
+	_, err = newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), noResponseURL, nil, true, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNewURLInspectorWithSitemaps(t *testing.T) {
+	i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), rootURL, nil, true, []string{sitemapCheckURL + "/sitemap-gzipped.xml.gz"})
+
+	assert.Nil(t, err)
+	assert.Empty(t, i.Warnings())
+
+	u, err := url.Parse(sitemapCheckURL + "/baz")
+	assert.Nil(t, err)
+	assert.True(t, i.Inspect(u))
+
+	u, err = url.Parse(existentURL)
+	assert.Nil(t, err)
+	assert.False(t, i.Inspect(u))
+}
+
+func TestNewURLInspectorWithSitemapsInRobotsTxt(t *testing.T) {
+	i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), sitemapCheckURL, nil, true, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sitemap not found at " + sitemapCheckURL + "/sitemap-missing.xml"}, i.Warnings())
+
+	u, err := url.Parse(sitemapCheckURL + "/baz")
+	assert.Nil(t, err)
+	assert.True(t, i.Inspect(u))
 }
 
 func TestNewURLInspectorWithSelfCertifiedServer(t *testing.T) {
-	for _, bs := range [][2]bool{{true, false}, {false, true}, {true, true}} {
-		_, err := newURLInspector(&fasthttp.Client{}, selfCertificateURL, bs[0], bs[1])
-		assert.NotNil(t, err)
+	_, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), selfCertificateURL, nil, true, nil)
+	assert.NotNil(t, err)
 
-		_, err = newURLInspector(
-			&fasthttp.Client{TLSConfig: &tls.Config{InsecureSkipVerify: true}},
-			selfCertificateURL, bs[0], bs[1])
-		assert.Nil(t, err)
-	}
+	_, err = newURLInspector(
+		newSitemapClient(&fasthttp.Client{TLSConfig: &tls.Config{InsecureSkipVerify: true}}, fetcherOptions{}),
+		selfCertificateURL, nil, true, nil)
+	assert.Nil(t, err)
 }
 
 func TestURLInspectorInspectWithSitemapXML(t *testing.T) {
-	i, err := newURLInspector(&fasthttp.Client{}, rootURL, false, true)
+	i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), rootURL, nil, true, nil)
 	assert.Nil(t, err)
 
 	for _, s := range []string{rootURL, existentURL} {
@@ -68,7 +105,8 @@ func TestURLInspectorInspectWithSitemapXML(t *testing.T) {
 }
 
 func TestURLInspectorInspectWithRobotsTxt(t *testing.T) {
-	i, err := newURLInspector(&fasthttp.Client{}, rootURL, true, false)
+	r := newRobotsTxtCache(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), defaultRobotsTxtUserAgent)
+	i, err := newURLInspector(newSitemapClient(&fasthttp.Client{}, fetcherOptions{}), rootURL, &r, false, nil)
 	assert.Nil(t, err)
 
 	for _, s := range []string{rootURL, existentURL} {
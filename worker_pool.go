@@ -0,0 +1,160 @@
+package muffet
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// linkTaskPriority is a priority of tasks of fetching links. Tasks always run
+// before new pages are checked so that only a few pages are in progress at a
+// time.
+const linkTaskPriority = 0
+
+type task struct {
+	priority, sequence int
+	run                func(context.Context)
+}
+
+type taskHeap []task
+
+func (h taskHeap) Len() int {
+	return len(h)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+
+	return h[i].sequence < h[j].sequence
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	*h = append(*h, x.(task))
+}
+
+func (h *taskHeap) Pop() interface{} {
+	t := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return t
+}
+
+// workerPool runs tasks in a priority queue and then pages in a frontier with
+// a fixed number of workers.
+type workerPool struct {
+	workers  int
+	mutex    *sync.Mutex
+	cond     *sync.Cond
+	tasks    *taskHeap
+	pages    *frontier
+	pending  *int
+	sequence *int
+}
+
+// newWorkerPool creates a worker pool with a number of workers which keeps up
+// to a number of waiting pages in memory.
+func newWorkerPool(n, m int) workerPool {
+	x := &sync.Mutex{}
+	return workerPool{n, x, sync.NewCond(x), &taskHeap{}, newFrontier(m), new(int), new(int)}
+}
+
+// Add queues a task. Tasks with smaller priorities run first and ones with
+// the same priority run in order.
+func (p workerPool) Add(priority int, f func(context.Context)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	heap.Push(p.tasks, task{priority, *p.sequence, f})
+	*p.sequence++
+	*p.pending++
+
+	p.cond.Signal()
+}
+
+// AddPage queues a page at a depth. Pages are run in the order of their
+// depths after all tasks.
+func (p workerPool) AddPage(x *page, d int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pages.Push(x, d)
+	*p.pending++
+
+	p.cond.Signal()
+}
+
+// Run runs tasks and pages with a function until all of them including ones
+// added by themselves finish. They are run even after a context is canceled so
+// that they can clean up their works. They should check the context before
+// doing any heavy work.
+func (p workerPool) Run(ctx context.Context, page func(context.Context, *page, int)) {
+	defer p.pages.Close()
+
+	w := sync.WaitGroup{}
+
+	for i := 0; i < p.workers; i++ {
+		w.Add(1)
+
+		go func() {
+			defer w.Done()
+
+			for {
+				t, e, ok := p.next()
+
+				if !ok {
+					return
+				} else if t.run != nil {
+					t.run(ctx)
+				} else {
+					page(ctx, e.page, e.depth)
+				}
+
+				p.done()
+			}
+		}()
+	}
+
+	w.Wait()
+}
+
+// Err returns an error on loading pages waiting to be run. Pages which fail
+// to be loaded are never run.
+func (p workerPool) Err() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.pages.Err()
+}
+
+func (p workerPool) next() (task, pageEntry, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for p.tasks.Len() == 0 && p.pages.Len() == 0 && *p.pending != 0 {
+		p.cond.Wait()
+	}
+
+	if p.tasks.Len() != 0 {
+		return heap.Pop(p.tasks).(task), pageEntry{}, true
+	} else if e, ok := p.pages.Pop(); ok {
+		return task{}, e, true
+	}
+
+	return task{}, pageEntry{}, false
+}
+
+func (p workerPool) done() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	*p.pending--
+
+	if *p.pending == 0 {
+		p.cond.Broadcast()
+	}
+}
@@ -0,0 +1,108 @@
+package muffet
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWorkerPool(t *testing.T) {
+	newWorkerPool(1, 1)
+}
+
+func TestWorkerPoolRun(t *testing.T) {
+	x := int32(0)
+
+	p := newWorkerPool(42, 1)
+
+	for i := 0; i < 100; i++ {
+		p.Add(0, func(context.Context) { atomic.AddInt32(&x, 1) })
+	}
+
+	p.Run(context.Background(), nil)
+
+	assert.Equal(t, int32(100), x)
+}
+
+func TestWorkerPoolRunWithoutTasks(t *testing.T) {
+	newWorkerPool(42, 1).Run(context.Background(), nil)
+}
+
+func TestWorkerPoolRunWithNestedTasks(t *testing.T) {
+	x := int32(0)
+
+	p := newWorkerPool(2, 1)
+
+	var f func(int) func(context.Context)
+	f = func(n int) func(context.Context) {
+		return func(context.Context) {
+			atomic.AddInt32(&x, 1)
+
+			if n > 0 {
+				p.Add(0, f(n-1))
+				p.Add(0, f(n-1))
+			}
+		}
+	}
+
+	p.Add(0, f(9))
+	p.Run(context.Background(), nil)
+
+	assert.Equal(t, int32(1023), x)
+}
+
+func TestWorkerPoolRunWithPriorities(t *testing.T) {
+	is := []int{}
+
+	p := newWorkerPool(1, 1)
+
+	for _, i := range []int{3, 1, 2, 1, 0} {
+		i := i
+		p.Add(i, func(context.Context) { is = append(is, i) })
+	}
+
+	p.Run(context.Background(), nil)
+
+	assert.Equal(t, []int{0, 1, 1, 2, 3}, is)
+}
+
+func TestWorkerPoolRunWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := []error{}
+
+	p := newWorkerPool(1, 1)
+	p.Add(0, func(ctx context.Context) { errs = append(errs, ctx.Err()) })
+	p.Run(ctx, nil)
+
+	assert.Equal(t, []error{context.Canceled}, errs)
+}
+
+func TestWorkerPoolRunWithPages(t *testing.T) {
+	ss := []string{}
+
+	p := newWorkerPool(1, 2)
+
+	for i, d := range []int{2, 0, 1, 0, 3} {
+		x, err := newPage("https://foo.com/"+strconv.Itoa(i), nil, newScraper(nil, defaultSchemes))
+		assert.Nil(t, err)
+
+		p.AddPage(x, d)
+	}
+
+	p.Add(linkTaskPriority, func(context.Context) { ss = append(ss, "task") })
+	p.Run(context.Background(), func(_ context.Context, x *page, d int) {
+		ss = append(ss, strings.TrimPrefix(x.URL().String(), "https://foo.com/"))
+
+		if d == 0 {
+			p.Add(linkTaskPriority, func(context.Context) { ss = append(ss, "task") })
+		}
+	})
+
+	assert.Equal(t, []string{"task", "1", "task", "3", "task", "2", "0", "4"}, ss)
+}